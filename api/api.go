@@ -0,0 +1,42 @@
+// Package api содержит контракты сервиса: спецификацию OpenAPI и proto-файлы.
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed openapi.json
+var OpenAPI []byte
+
+//go:embed swagger.html
+var swaggerUI []byte
+
+// swaggerAssets — скрипт и стили Swagger UI, см. swagger-ui/README.md.
+//
+//go:embed swagger-ui/swagger-ui.css swagger-ui/swagger-ui-bundle.js
+var swaggerAssets embed.FS
+
+// SpecHandler отдает спецификацию OpenAPI.
+func SpecHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(OpenAPI)
+}
+
+// SwaggerUIHandler отдает страницу Swagger UI, которая читает /api/openapi.json.
+func SwaggerUIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(swaggerUI)
+}
+
+// SwaggerAssetsHandler отдает скрипт и стили страницы Swagger UI по /api/docs/{file}.
+var SwaggerAssetsHandler = http.StripPrefix("/api/docs/", http.FileServerFS(mustSub(swaggerAssets, "swagger-ui")))
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
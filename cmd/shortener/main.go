@@ -2,86 +2,369 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/Oleg2210/goshortener/internal/certs"
 	"github.com/Oleg2210/goshortener/internal/config"
+	"github.com/Oleg2210/goshortener/internal/grpcserver"
 	"github.com/Oleg2210/goshortener/internal/handler"
+	"github.com/Oleg2210/goshortener/internal/metrics"
 	"github.com/Oleg2210/goshortener/internal/repository"
+	"github.com/Oleg2210/goshortener/internal/rules"
 	"github.com/Oleg2210/goshortener/internal/service"
+	"github.com/Oleg2210/goshortener/internal/tracing"
 	compres "github.com/Oleg2210/goshortener/pkg/middleware/compress"
 	"github.com/Oleg2210/goshortener/pkg/middleware/logging"
+	"github.com/Oleg2210/goshortener/pkg/middleware/secure"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
-func chooseStorage(logger *zap.Logger) repository.URLRepository {
-	if config.DatabaseInfo != "" {
-		repo, err := repository.NewDBRepository(config.DatabaseInfo)
+// dbOptions переносит настройки базы из конфигурации в параметры хранилища.
+func dbOptions(cfg config.Config) repository.DBOptions {
+	return repository.DBOptions{
+		MaxOpenConns:         cfg.DBMaxOpenConns,
+		MaxIdleConns:         cfg.DBMaxIdleConns,
+		ConnMaxLifetime:      time.Duration(cfg.DBConnMaxLifetime),
+		ConnMaxIdleTime:      time.Duration(cfg.DBConnMaxIdleTime),
+		ConnectAttempts:      cfg.DBConnectAttempts,
+		ConnectBackoff:       time.Duration(cfg.DBConnectBackoff),
+		AutoMigrate:          cfg.DBAutoMigrate,
+		MigrationLockTimeout: time.Duration(cfg.DBMigrationLockTimeout),
+		QueryTimeout:         time.Duration(cfg.DBQueryTimeout),
+		QueryRetries:         cfg.DBQueryRetries,
+		BreakerThreshold:     cfg.DBBreakerThreshold,
+		BreakerCooldown:      time.Duration(cfg.DBBreakerCooldown),
 
-		if err == nil {
-			return repo
+		Replicas:             cfg.DatabaseReplicaDSNs,
+		ReplicaCheckInterval: time.Duration(cfg.DBReplicaCheckInterval),
+		ReadYourWritesWindow: time.Duration(cfg.DBReadYourWritesWindow),
+	}
+}
+
+// chooseStorage выбирает хранилище по конфигурации. Заданная, но недоступная
+// база — ошибка: молча подменять ее памятью значит потерять ссылки.
+func chooseStorage(ctx context.Context, cfg config.Config, logger *zap.Logger) (repository.URLRepository, error) {
+	if repository.IsSQLiteDSN(cfg.DatabaseDSN) {
+		repo, err := repository.NewSQLiteRepository(ctx, cfg.DatabaseDSN, dbOptions(cfg))
+		if err != nil {
+			return nil, err
+		}
+
+		if err := metrics.RegisterDBStats(repo.DB, "sqlite"); err != nil {
+			logger.Error("failed to register db stats", zap.Error(err))
+		}
+		return repository.NewInstrumentedRepository(repo, "sqlite"), nil
+	}
+	if len(cfg.DatabaseShardDSNs) > 0 {
+		repo, err := repository.NewShardedRepository(ctx, cfg.DatabaseDSN, cfg.DatabaseShardDSNs, cfg.DBPreviousShards, dbOptions(cfg))
+		if err != nil {
+			return nil, err
+		}
+
+		// номера шардов те же, что в `shortener shards status`
+		stats := map[string]*repository.DBRepository{"postgres-index": repo.Index}
+		for i, shard := range repo.Shards {
+			stats[fmt.Sprintf("postgres-shard-%d", i)] = shard
+		}
+		for name, db := range stats {
+			if err := metrics.RegisterDBStats(db.DB, name); err != nil {
+				logger.Error("failed to register db stats", zap.String("db", name), zap.Error(err))
+			}
+		}
+		return repository.NewInstrumentedRepository(repo, "sharded"), nil
+	}
+	if cfg.DatabaseDSN != "" && cfg.DatabaseDriver == "pgxpool" {
+		repo, err := repository.NewPgxRepository(ctx, cfg.DatabaseDSN, dbOptions(cfg))
+		if err != nil {
+			return nil, err
 		}
 
-		logger.Error("failed to create db repo", zap.Error(err))
+		if err := metrics.RegisterPgxPoolStats(repo.Pool, "postgres"); err != nil {
+			logger.Error("failed to register db stats", zap.Error(err))
+		}
+		return repository.NewInstrumentedRepository(repo, "pgx"), nil
 	}
+	if cfg.DatabaseDSN != "" {
+		repo, err := repository.NewDBRepository(ctx, cfg.DatabaseDSN, dbOptions(cfg))
+		if err != nil {
+			return nil, err
+		}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+		if err := metrics.RegisterDBStats(repo.DB, "postgres"); err != nil {
+			logger.Error("failed to register db stats", zap.Error(err))
+		}
+		return repository.NewInstrumentedRepository(repo, "db"), nil
+	}
 
-	if config.FileStoragePath != "" {
-		repo, err := repository.NewFileRepository(ctx, config.FileStoragePath)
+	if cfg.FileStoragePath != "" {
+		repo, err := repository.NewFileRepository(ctx, cfg.FileStoragePath)
 
 		if err == nil {
-			return repo
+			return repository.NewInstrumentedRepository(repo, "file"), nil
 		}
 
 		logger.Error("failed to create file repo", zap.Error(err))
 	}
 
-	return repository.NewMemoryRepository()
+	return repository.NewInstrumentedRepository(repository.NewMemoryRepository(), "memory"), nil
 }
 
 func main() {
-	config.Load()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "shards" {
+		os.Exit(runShards(os.Args[2:]))
+	}
+
+	cfg, err := config.Load(os.Args[0], os.Args[1:])
+	if errors.Is(err, config.ErrPrintConfig) {
+		dump, err := cfg.Dump()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to dump config: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(dump))
+
+		// ошибки сообщаются после печати, чтобы было видно, из каких значений они взялись
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+			os.Exit(2)
+		}
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		os.Exit(2)
+	}
+
 	router := chi.NewRouter()
 
-	logger, err := zap.NewProduction()
+	logger, err := logging.NewLogger(cfg.LogLevel, cfg.LogFormat)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to init zap logger: %v\n", err)
 		os.Exit(1)
 	}
 
-	repo := chooseStorage(logger)
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Endpoint:    cfg.OTLPEndpoint,
+		Insecure:    cfg.OTLPInsecure,
+		SampleRatio: cfg.TraceSampleRatio,
+	})
+	if err != nil {
+		logger.Fatal("failed to init tracing", zap.Error(err))
+	}
+	defer shutdownTracing(context.Background())
+
+	repo, err := chooseStorage(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Fatal("failed to open storage", zap.Error(err))
+	}
 
 	shortenerService := service.NewShortenerService(
 		repo,
-		config.MinLength,
-		config.MaxLength,
+		cfg.MinLength,
+		cfg.MaxLength,
 	)
 
 	app := handler.App{
 		ShortenerService: shortenerService,
 		Logger:           logger,
+		BaseURL:          cfg.BaseURL,
+		BatchChunkSize:   cfg.BatchChunkSize,
+		Redirect:         cfg.RedirectPolicy(),
+	}
+
+	if cfg.GeoIPDatabase != "" {
+		geo, err := rules.OpenGeoIP(cfg.GeoIPDatabase)
+		if err != nil {
+			logger.Error("failed to open geoip database, country rules are disabled", zap.Error(err))
+		} else {
+			defer geo.Close()
+			app.Geo = geo
+		}
 	}
 
-	router.Use(logging.LoggingMiddleware(logger))
-	router.Use(compres.GzipMiddleware)
-	router.Get("/{id}", app.HandleGet)
-	router.Post("/", app.HandlePost)
-	router.Post("/api/shorten", app.HandlePostJSON)
-	router.Post("/api/shorten/batch", app.HandlePostBatchJSON)
-	router.Get("/ping", app.HandlePing)
+	bodyMode, _ := logging.ParseBodyMode(cfg.LogBodyMode)
+
+	router.Use(logging.RequestIDMiddleware)
+	router.Use(tracing.Middleware)
+	router.Use(metrics.HTTPMiddleware)
+	// сжатие снаружи логирования: в лог попадает уже распакованное тело запроса
+	compressOptions := compres.DefaultOptions()
+	compressOptions.MinSize = cfg.CompressMinSize
+	compressOptions.MaxDecompressedSize = cfg.MaxDecompressedSize
+	router.Use(compres.Middleware(compressOptions))
+	router.Use(logging.LoggingMiddleware(logger, logging.Options{
+		BodyMode:          bodyMode,
+		BodyMaxBytes:      cfg.LogBodyMaxBytes,
+		RedactQueryParams: cfg.LogRedactQueryParams,
+		RedactJSONFields:  cfg.LogRedactJSONFields,
+		SampleInitial:     cfg.LogSampleInitial,
+		SampleThereafter:  cfg.LogSampleThereafter,
+	}))
+	if cfg.EnableHTTPS && cfg.HSTSMaxAge > 0 {
+		router.Use(secure.HSTSMiddleware(time.Duration(cfg.HSTSMaxAge)))
+	}
+	app.Register(router, handler.RouteOptions{
+		MaxBodySize:      cfg.MaxBodySize,
+		MaxBatchBodySize: cfg.MaxBatchBodySize,
+		SwaggerUI:        cfg.SwaggerUI,
+	})
+	router.Method(http.MethodGet, "/metrics", metrics.Handler())
+
+	var grpcServer *grpc.Server
+	if cfg.GRPCAddress != "" {
+		grpcServer = grpcserver.New(&grpcserver.Server{
+			ShortenerService: shortenerService,
+			Logger:           logger,
+			BaseURL:          cfg.BaseURL,
+		}, cfg.GRPCAuthToken)
+
+		listener, err := net.Listen("tcp", cfg.GRPCAddress)
+		if err != nil {
+			logger.Fatal("failed to listen grpc address", zap.Error(err))
+		}
+
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Error("grpc server stopped", zap.Error(err))
+			}
+		}()
+	}
 
 	server := &http.Server{
-		Addr:         config.PortAddres,
+		Addr:         cfg.ServerAddress,
 		Handler:      router,
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 45 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
-	server.ListenAndServe()
+	var redirectServer *http.Server
+	if cfg.EnableHTTPS {
+		if err := configureTLS(cfg, server); err != nil {
+			logger.Fatal("failed to configure tls", zap.Error(err))
+		}
+		redirectServer, err = startRedirect(cfg, logger)
+		if err != nil {
+			logger.Fatal("failed to listen http redirect address", zap.Error(err))
+		}
+	}
+
+	// по сигналу HTTP- и gRPC-серверы дожидаются текущих запросов, затем хранилище
+	// закрывается и успевает записать отложенное (клики в файловом хранилище)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error("failed to shut down server", zap.Error(err))
+		}
+		if redirectServer != nil {
+			if err := redirectServer.Shutdown(shutdownCtx); err != nil {
+				logger.Error("failed to shut down http redirect server", zap.Error(err))
+			}
+		}
+		if grpcServer != nil {
+			stopGRPC(shutdownCtx, grpcServer)
+		}
+	}()
+
+	if !cfg.EnableHTTPS {
+		err = server.ListenAndServe()
+	} else {
+		err = server.ListenAndServeTLS("", "")
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		logger.Fatal("server stopped", zap.Error(err))
+	}
+	<-stopped
+
+	if closer, ok := repo.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logger.Error("failed to close storage", zap.Error(err))
+		}
+	}
+}
+
+// stopGRPC дожидается текущих вызовов gRPC, но не дольше ctx: затем соединения рвутся.
+func stopGRPC(ctx context.Context, s *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.Stop()
+		<-done
+	}
+}
+
+// configureTLS задает серверу сертификат из конфигурации или самоподписанный.
+func configureTLS(cfg config.Config, server *http.Server) error {
+	var hosts []string
+	if u, err := url.Parse(cfg.BaseURL); err == nil {
+		hosts = append(hosts, u.Hostname())
+	}
+
+	cert, err := certs.Load(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSCacheDir, hosts...)
+	if err != nil {
+		return fmt.Errorf("load tls certificate: %w", err)
+	}
+
+	server.TLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	return nil
+}
+
+// startRedirect запускает HTTP-сервер, перенаправляющий на HTTPS, если задан
+// HTTPRedirectAddress. Порт занимается сразу, чтобы ошибка остановила запуск.
+func startRedirect(cfg config.Config, logger *zap.Logger) (*http.Server, error) {
+	if cfg.HTTPRedirectAddress == "" {
+		return nil, nil
+	}
+
+	listener, err := net.Listen("tcp", cfg.HTTPRedirectAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	_, httpsPort, _ := net.SplitHostPort(cfg.ServerAddress)
+	redirectServer := &http.Server{
+		Addr:         cfg.HTTPRedirectAddress,
+		Handler:      secure.RedirectHandler(httpsPort),
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		if err := redirectServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("http redirect listener stopped", zap.Error(err))
+		}
+	}()
+	return redirectServer, nil
 }
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Oleg2210/goshortener/internal/config"
+	"github.com/Oleg2210/goshortener/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// умолчания базы задаются в config отдельно, чтобы он не зависел от repository
+func TestDefaultConfigMatchesDBOptions(t *testing.T) {
+	assert.Equal(t, repository.DefaultDBOptions(), dbOptions(config.Default()))
+}
+
+func TestStopGRPCIsBounded(t *testing.T) {
+	listener := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(listener)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Watch не завершается сам, поэтому GracefulStop без ограничения ждал бы вечно
+	stream, err := healthpb.NewHealthClient(conn).Watch(t.Context(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	stopGRPC(ctx, s)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	_, err = stream.Recv()
+	assert.Error(t, err, "the call is cut off")
+}
+
+func TestStartRedirect(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer busy.Close()
+
+	cfg := config.Default()
+	cfg.ServerAddress = "127.0.0.1:8443"
+	cfg.HTTPRedirectAddress = busy.Addr().String()
+	_, err = startRedirect(cfg, zap.NewNop())
+	assert.Error(t, err, "a taken port stops startup")
+
+	cfg.HTTPRedirectAddress = ""
+	server, err := startRedirect(cfg, zap.NewNop())
+	require.NoError(t, err)
+	assert.Nil(t, server)
+
+	addr := busy.Addr().String()
+	busy.Close()
+	cfg.HTTPRedirectAddress = addr
+	server, err = startRedirect(cfg, zap.NewNop())
+	require.NoError(t, err)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get("http://" + addr + "/abc")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusPermanentRedirect, resp.StatusCode)
+
+	require.NoError(t, server.Shutdown(t.Context()))
+	_, err = client.Get("http://" + addr + "/abc")
+	assert.Error(t, err, "the listener is closed on shutdown")
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Oleg2210/goshortener/internal/config"
+	"github.com/Oleg2210/goshortener/internal/repository"
+)
+
+const migrateUsage = `usage: shortener migrate <command> [flags]
+
+commands:
+  up         apply all pending migrations
+  down [N]   roll back the last N migrations (default 1)
+  status     list embedded migrations and whether they are applied
+  version    print the current schema version
+
+The database is taken from the server config: DATABASE_DSN, -d or -c.`
+
+// runMigrate выполняет подкоманду migrate и возвращает код выхода.
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	command, args := args[0], args[1:]
+	steps := 1
+	switch command {
+	case "up", "status", "version":
+	case "down":
+		if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "invalid number of steps %q\n", args[0])
+				return 2
+			}
+			steps, args = n, args[1:]
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s\n", command, migrateUsage)
+		return 2
+	}
+
+	cfg, err := config.Load("shortener migrate "+command, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		return 2
+	}
+	if cfg.DatabaseDSN == "" {
+		fmt.Fprintln(os.Stderr, "database dsn is not set: use DATABASE_DSN or -d")
+		return 2
+	}
+
+	migrator, err := repository.NewMigrator(cfg.DatabaseDSN, time.Duration(cfg.DBMigrationLockTimeout))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	}
+	defer migrator.Close()
+
+	switch command {
+	case "up":
+		err = migrator.Up()
+	case "down":
+		err = migrator.Down(steps)
+	case "status":
+		err = printStatus(migrator)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate %s: %v\n", command, err)
+		return 1
+	}
+
+	if err := printVersion(migrator); err != nil {
+		fmt.Fprintf(os.Stderr, "migrate %s: %v\n", command, err)
+		return 1
+	}
+	return 0
+}
+
+func printStatus(migrator *repository.Migrator) error {
+	list, _, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range list {
+		state := "pending"
+		if m.Applied {
+			state = "applied"
+		}
+		fmt.Printf("%06d  %-8s %s\n", m.Version, state, m.Name)
+	}
+	return nil
+}
+
+func printVersion(migrator *repository.Migrator) error {
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		fmt.Printf("version %d (dirty)\n", version)
+	} else {
+		fmt.Printf("version %d\n", version)
+	}
+	return nil
+}
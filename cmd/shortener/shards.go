@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/Oleg2210/goshortener/internal/config"
+	"github.com/Oleg2210/goshortener/internal/repository"
+)
+
+const shardsUsage = `usage: shortener shards <command> [flags]
+
+commands:
+  status      print the number of links on each shard and its share of the ring
+  rebalance   move links to the shards that own them and fill the dedup index
+
+Shards are taken from the server config: DATABASE_SHARD_DSNS and DATABASE_DSN.
+To add shards, append their dsns, restart the servers with DB_PREVIOUS_SHARDS
+set to the old number of shards, run rebalance, then unset DB_PREVIOUS_SHARDS.`
+
+// страница перебалансировки: столько записей читается и переносится за шаг
+const rebalanceBatch = 1000
+
+// runShards выполняет подкоманду shards и возвращает код выхода.
+func runShards(args []string) int {
+	if len(args) == 0 || (args[0] != "status" && args[0] != "rebalance") {
+		fmt.Fprintln(os.Stderr, shardsUsage)
+		return 2
+	}
+	command, args := args[0], args[1:]
+
+	cfg, err := config.Load("shortener shards "+command, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		return 2
+	}
+	if len(cfg.DatabaseShardDSNs) == 0 {
+		fmt.Fprintln(os.Stderr, "shards are not set: use DATABASE_SHARD_DSNS or -db-shards")
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	repo, err := repository.NewShardedRepository(ctx, cfg.DatabaseDSN, cfg.DatabaseShardDSNs, cfg.DBPreviousShards, dbOptions(cfg))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "shards: %v\n", err)
+		return 1
+	}
+	defer repo.Close()
+
+	if command == "rebalance" {
+		stats, err := repo.Rebalance(ctx, rebalanceBatch, func(s repository.RebalanceStats) {
+			fmt.Fprintf(os.Stderr, "scanned %d, moved %d\n", s.Scanned, s.Moved)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "shards rebalance: %v\n", err)
+			return 1
+		}
+		fmt.Printf("scanned %d, moved %d\n", stats.Scanned, stats.Moved)
+	}
+
+	stats, err := repo.Stats(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "shards %s: %v\n", command, err)
+		return 1
+	}
+	for _, s := range stats {
+		fmt.Printf("shard %d  %12d links  %5.1f%% of ring\n", s.Shard, s.Records, s.Share*100)
+	}
+	return 0
+}
@@ -0,0 +1,38 @@
+package certs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfSignedIsCached(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := Load("", "", dir, "short.example")
+	require.NoError(t, err)
+	require.NotNil(t, first.Leaf)
+	assert.Contains(t, first.Leaf.DNSNames, "short.example")
+
+	second, err := Load("", "", dir)
+	require.NoError(t, err)
+	assert.Equal(t, first.Leaf.SerialNumber, second.Leaf.SerialNumber)
+}
+
+func TestSelfSignedFollowsHosts(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := Load("", "", dir, "short.example")
+	require.NoError(t, err)
+
+	second, err := Load("", "", dir, "new.example", "10.0.0.1")
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Leaf.SerialNumber, second.Leaf.SerialNumber)
+	assert.NoError(t, second.Leaf.VerifyHostname("new.example"))
+	assert.NoError(t, second.Leaf.VerifyHostname("10.0.0.1"))
+
+	third, err := Load("", "", dir, "new.example", "localhost")
+	require.NoError(t, err)
+	assert.Equal(t, second.Leaf.SerialNumber, third.Leaf.SerialNumber)
+}
@@ -1,54 +1,523 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
-	"log"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/Oleg2210/goshortener/internal/entities"
 	"github.com/ilyakaznacheev/cleanenv"
+	"go.uber.org/zap/zapcore"
 )
 
-// минимальная длина id
-const MinLength = 5
+// redacted подставляется вместо секретов при выводе конфигурации
+const redacted = "xxxxx"
 
-// максимальная длина id
-const MaxLength = 10
+// sqliteScheme — префикс DSN, по которому выбирается SQLite (см. repository.IsSQLiteDSN)
+const sqliteScheme = "sqlite://"
 
-var (
-	PortAddres      string
-	ResolveAddress  string
-	FileStoragePath string
-	DatabaseInfo    string
-)
+// ErrPrintConfig возвращается из Load, если запрошен режим --print-config
+var ErrPrintConfig = errors.New("print config requested")
+
+type Config struct {
+	ServerAddress   string `json:"server_address" yaml:"server_address" env:"SERVER_ADDRESS"`
+	BaseURL         string `json:"base_url" yaml:"base_url" env:"BASE_URL"`
+	FileStoragePath string `json:"file_storage_path" yaml:"file_storage_path" env:"FILE_STORAGE_PATH"`
+	// DSN Postgres или sqlite://путь/к/файлу.db
+	DatabaseDSN string `json:"database_dsn" yaml:"database_dsn" env:"DATABASE_DSN"`
+	// драйвер Postgres: stdlib (database/sql) или pgxpool (нативный пул pgx)
+	DatabaseDriver string `json:"database_driver" yaml:"database_driver" env:"DATABASE_DRIVER"`
+
+	// пул соединений с базой
+	DBMaxOpenConns    int      `json:"db_max_open_conns" yaml:"db_max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns    int      `json:"db_max_idle_conns" yaml:"db_max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime Duration `json:"db_conn_max_lifetime" yaml:"db_conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	DBConnMaxIdleTime Duration `json:"db_conn_max_idle_time" yaml:"db_conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+	// попытки дождаться базы при старте, пауза между ними удваивается
+	DBConnectAttempts int      `json:"db_connect_attempts" yaml:"db_connect_attempts" env:"DB_CONNECT_ATTEMPTS"`
+	DBConnectBackoff  Duration `json:"db_connect_backoff" yaml:"db_connect_backoff" env:"DB_CONNECT_BACKOFF"`
+	// применять ли миграции при старте; выключенные применяются через `shortener migrate up`
+	DBAutoMigrate bool `json:"db_auto_migrate" yaml:"db_auto_migrate" env:"DB_AUTO_MIGRATE"`
+	// сколько ждать, пока миграции применяет другой экземпляр
+	DBMigrationLockTimeout Duration `json:"db_migration_lock_timeout" yaml:"db_migration_lock_timeout" env:"DB_MIGRATION_LOCK_TIMEOUT"`
+	// таймаут запроса и число повторов после временной ошибки
+	DBQueryTimeout Duration `json:"db_query_timeout" yaml:"db_query_timeout" env:"DB_QUERY_TIMEOUT"`
+	DBQueryRetries int      `json:"db_query_retries" yaml:"db_query_retries" env:"DB_QUERY_RETRIES"`
+	// после стольких отказов подряд запросы к базе отклоняются на db_breaker_cooldown; 0 — выключено
+	DBBreakerThreshold int      `json:"db_breaker_threshold" yaml:"db_breaker_threshold" env:"DB_BREAKER_THRESHOLD"`
+	DBBreakerCooldown  Duration `json:"db_breaker_cooldown" yaml:"db_breaker_cooldown" env:"DB_BREAKER_COOLDOWN"`
+	// реплики только для чтения: на них уходят редиректы и чтение ссылок
+	DatabaseReplicaDSNs    StringList `json:"database_replica_dsns" yaml:"database_replica_dsns" env:"DATABASE_REPLICA_DSNS"`
+	DBReplicaCheckInterval Duration   `json:"db_replica_check_interval" yaml:"db_replica_check_interval" env:"DB_REPLICA_CHECK_INTERVAL"`
+	// сколько после записи ссылка читается с primary
+	DBReadYourWritesWindow Duration `json:"db_read_your_writes_window" yaml:"db_read_your_writes_window" env:"DB_READ_YOUR_WRITES_WINDOW"`
+	// шарды urls; database_dsn тогда хранит глобальный индекс адресов. Новые шарды добавляются только в конец
+	DatabaseShardDSNs StringList `json:"database_shard_dsns" yaml:"database_shard_dsns" env:"DATABASE_SHARD_DSNS"`
+	// число шардов до последнего добавления, пока идет перебалансировка; 0 — шарды не менялись
+	DBPreviousShards int `json:"db_previous_shards" yaml:"db_previous_shards" env:"DB_PREVIOUS_SHARDS"`
+
+	// минимальная длина id
+	MinLength int `json:"min_id_length" yaml:"min_id_length" env:"MIN_ID_LENGTH"`
+	// максимальная длина id
+	MaxLength int `json:"max_id_length" yaml:"max_id_length" env:"MAX_ID_LENGTH"`
+
+	EnableHTTPS bool   `json:"enable_https" yaml:"enable_https" env:"ENABLE_HTTPS"`
+	TLSCertFile string `json:"tls_cert_file" yaml:"tls_cert_file" env:"TLS_CERT_FILE"`
+	TLSKeyFile  string `json:"tls_key_file" yaml:"tls_key_file" env:"TLS_KEY_FILE"`
+	// каталог, где кешируется самоподписанный сертификат
+	TLSCacheDir string `json:"tls_cache_dir" yaml:"tls_cache_dir" env:"TLS_CACHE_DIR"`
+	// адрес http-листенера, перенаправляющего на https; пустой — выключен
+	HTTPRedirectAddress string   `json:"http_redirect_address" yaml:"http_redirect_address" env:"HTTP_REDIRECT_ADDRESS"`
+	HSTSMaxAge          Duration `json:"hsts_max_age" yaml:"hsts_max_age" env:"HSTS_MAX_AGE"`
+
+	// адрес gRPC-сервера; пустой — gRPC выключен
+	GRPCAddress   string `json:"grpc_address" yaml:"grpc_address" env:"GRPC_ADDRESS"`
+	GRPCAuthToken string `json:"grpc_auth_token" yaml:"grpc_auth_token" env:"GRPC_AUTH_TOKEN"`
+
+	// адрес OTLP/HTTP коллектора (host:port); пустой — экспорт трасс выключен
+	OTLPEndpoint     string  `json:"otlp_endpoint" yaml:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	OTLPInsecure     bool    `json:"otlp_insecure" yaml:"otlp_insecure" env:"OTEL_EXPORTER_OTLP_INSECURE"`
+	TraceSampleRatio float64 `json:"trace_sample_ratio" yaml:"trace_sample_ratio" env:"OTEL_TRACES_SAMPLER_ARG"`
+
+	LogLevel  string `json:"log_level" yaml:"log_level" env:"LOG_LEVEL"`
+	LogFormat string `json:"log_format" yaml:"log_format" env:"LOG_FORMAT"`
+	// off, truncated или redacted
+	LogBodyMode          string     `json:"log_body_mode" yaml:"log_body_mode" env:"LOG_BODY_MODE"`
+	LogBodyMaxBytes      int        `json:"log_body_max_bytes" yaml:"log_body_max_bytes" env:"LOG_BODY_MAX_BYTES"`
+	LogRedactQueryParams StringList `json:"log_redact_query_params" yaml:"log_redact_query_params" env:"LOG_REDACT_QUERY_PARAMS"`
+	LogRedactJSONFields  StringList `json:"log_redact_json_fields" yaml:"log_redact_json_fields" env:"LOG_REDACT_JSON_FIELDS"`
+	LogSampleInitial     int        `json:"log_sample_initial" yaml:"log_sample_initial" env:"LOG_SAMPLE_INITIAL"`
+	LogSampleThereafter  int        `json:"log_sample_thereafter" yaml:"log_sample_thereafter" env:"LOG_SAMPLE_THEREAFTER"`
 
-type envConfig struct {
-	PortAddres      string `env:"SERVER_ADDRESS"`
-	ResolveAddress  string `env:"BASE_URL"`
-	FileStoragePath string `env:"FILE_STORAGE_PATH"`
-	DatabaseInfo    string `env:"DATABASE_DSN"`
+	// ответы меньше этого размера не сжимаются
+	CompressMinSize int `json:"compress_min_size" yaml:"compress_min_size" env:"COMPRESS_MIN_SIZE"`
+	// предельный размер распакованного тела запроса
+	MaxDecompressedSize int64 `json:"max_decompressed_size" yaml:"max_decompressed_size" env:"MAX_DECOMPRESSED_SIZE"`
+
+	// лимиты тела запроса для POST / и /api/shorten и для батча
+	MaxBodySize      int64 `json:"max_body_size" yaml:"max_body_size" env:"MAX_BODY_SIZE"`
+	MaxBatchBodySize int64 `json:"max_batch_body_size" yaml:"max_batch_body_size" env:"MAX_BATCH_BODY_SIZE"`
+	BatchChunkSize   int   `json:"batch_chunk_size" yaml:"batch_chunk_size" env:"BATCH_CHUNK_SIZE"`
+
+	// отдавать Swagger UI на /api/docs
+	SwaggerUI bool `json:"swagger_ui" yaml:"swagger_ui" env:"SWAGGER_UI"`
+
+	// параметры редиректа для ссылок, у которых они не заданы при создании
+	RedirectCode int `json:"redirect_code" yaml:"redirect_code" env:"REDIRECT_CODE"`
+	// время кеширования редиректа; 0 — без заголовков кеширования, отрицательное — no-store
+	RedirectCacheMaxAge Duration `json:"redirect_cache_max_age" yaml:"redirect_cache_max_age" env:"REDIRECT_CACHE_MAX_AGE"`
+	ReferrerPolicy      string   `json:"referrer_policy" yaml:"referrer_policy" env:"REFERRER_POLICY"`
+	// перенос параметров запроса в адрес назначения: ignore, append, override или allowlist
+	QueryPassthrough string     `json:"query_passthrough" yaml:"query_passthrough" env:"QUERY_PASSTHROUGH"`
+	QueryAllowlist   StringList `json:"query_allowlist" yaml:"query_allowlist" env:"QUERY_ALLOWLIST"`
+
+	// файл базы MaxMind для условий правил по стране; пустой — страна не определяется
+	GeoIPDatabase string `json:"geoip_database" yaml:"geoip_database" env:"GEOIP_DATABASE"`
 }
 
-func Load() {
-	flag.StringVar(&PortAddres, "a", ":8080", "server address")
-	flag.StringVar(&ResolveAddress, "b", "http://localhost:8080", "base URL")
-	flag.StringVar(&FileStoragePath, "f", "urls-storage.json", "file storage")
-	flag.StringVar(&DatabaseInfo, "d", "", "database dsn")
-	flag.Parse()
+// StringList — список строк, который в флагах и переменных окружения задается через запятую.
+type StringList []string
 
-	var e envConfig
-	if err := cleanenv.ReadEnv(&e); err != nil {
-		log.Fatalf("config error: %v", err)
+func (l *StringList) String() string {
+	if l == nil {
+		return ""
 	}
+	return strings.Join(*l, ",")
+}
 
-	if e.PortAddres != "" {
-		PortAddres = e.PortAddres
+func (l *StringList) Set(value string) error {
+	*l = nil
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
 	}
-	if e.ResolveAddress != "" {
-		ResolveAddress = e.ResolveAddress
+	return nil
+}
+
+// SetValue позволяет cleanenv разбирать список из переменной окружения.
+func (l *StringList) SetValue(value string) error {
+	return l.Set(value)
+}
+
+// Duration — длительность, которая в файлах конфигурации и в --print-config
+// записывается как в флагах и переменных окружения: "5s", "1m30s".
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
 	}
-	if e.FileStoragePath != "" {
-		FileStoragePath = e.FileStoragePath
+	*d = Duration(parsed)
+	return nil
+}
+
+func Default() Config {
+	return Config{
+		ServerAddress:   ":8080",
+		BaseURL:         "http://localhost:8080",
+		FileStoragePath: "urls-storage.json",
+		MinLength:       5,
+		MaxLength:       10,
+
+		// совпадают с repository.DefaultDBOptions
+		DatabaseDriver:         "stdlib",
+		DBMaxOpenConns:         25,
+		DBMaxIdleConns:         10,
+		DBConnMaxLifetime:      Duration(30 * time.Minute),
+		DBConnMaxIdleTime:      Duration(5 * time.Minute),
+		DBConnectAttempts:      5,
+		DBConnectBackoff:       Duration(500 * time.Millisecond),
+		DBAutoMigrate:          true,
+		DBMigrationLockTimeout: Duration(5 * time.Minute),
+		DBQueryTimeout:         Duration(3 * time.Second),
+		DBQueryRetries:         2,
+		DBBreakerThreshold:     5,
+		DBBreakerCooldown:      Duration(10 * time.Second),
+
+		DBReplicaCheckInterval: Duration(5 * time.Second),
+		DBReadYourWritesWindow: Duration(5 * time.Second),
+
+		TLSCacheDir:      "tls-cache",
+		TraceSampleRatio: 1,
+
+		LogLevel:             "info",
+		LogFormat:            "json",
+		LogBodyMode:          "redacted",
+		LogBodyMaxBytes:      1024,
+		LogRedactQueryParams: StringList{"token", "access_token", "api_key", "key", "password", "secret", "sig", "signature"},
+		LogRedactJSONFields:  StringList{"password", "token", "secret"},
+		LogSampleInitial:     100,
+		LogSampleThereafter:  100,
+
+		CompressMinSize:     1024,
+		MaxDecompressedSize: 10 << 20,
+
+		MaxBodySize:      64 << 10,
+		MaxBatchBodySize: 256 << 20,
+		BatchChunkSize:   1000,
+
+		RedirectCode:     http.StatusTemporaryRedirect,
+		QueryPassthrough: string(entities.QueryIgnore),
+		QueryAllowlist:   StringList{"utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content"},
+	}
+}
+
+func (c *Config) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.ServerAddress, "a", c.ServerAddress, "server address")
+	fs.StringVar(&c.BaseURL, "b", c.BaseURL, "base URL")
+	fs.StringVar(&c.FileStoragePath, "f", c.FileStoragePath, "file storage")
+	fs.StringVar(&c.DatabaseDSN, "d", c.DatabaseDSN, "database dsn")
+	fs.StringVar(&c.DatabaseDriver, "db-driver", c.DatabaseDriver, "postgres driver: stdlib or pgxpool")
+	fs.IntVar(&c.DBMaxOpenConns, "db-max-open-conns", c.DBMaxOpenConns, "maximal open database connections, 0 is unlimited")
+	fs.IntVar(&c.DBMaxIdleConns, "db-max-idle-conns", c.DBMaxIdleConns, "maximal idle database connections")
+	fs.DurationVar((*time.Duration)(&c.DBConnMaxLifetime), "db-conn-max-lifetime", time.Duration(c.DBConnMaxLifetime), "database connection lifetime, 0 is unlimited")
+	fs.DurationVar((*time.Duration)(&c.DBConnMaxIdleTime), "db-conn-max-idle-time", time.Duration(c.DBConnMaxIdleTime), "database connection idle time, 0 is unlimited")
+	fs.IntVar(&c.DBConnectAttempts, "db-connect-attempts", c.DBConnectAttempts, "attempts to reach the database at startup")
+	fs.DurationVar((*time.Duration)(&c.DBConnectBackoff), "db-connect-backoff", time.Duration(c.DBConnectBackoff), "pause before the second startup attempt, doubled after each")
+	fs.BoolVar(&c.DBAutoMigrate, "db-auto-migrate", c.DBAutoMigrate, "apply database migrations at startup")
+	fs.DurationVar((*time.Duration)(&c.DBMigrationLockTimeout), "db-migration-lock-timeout", time.Duration(c.DBMigrationLockTimeout), "how long to wait for another instance applying migrations")
+	fs.DurationVar((*time.Duration)(&c.DBQueryTimeout), "db-query-timeout", time.Duration(c.DBQueryTimeout), "database query timeout, 0 disables")
+	fs.IntVar(&c.DBQueryRetries, "db-query-retries", c.DBQueryRetries, "retries of a query after a transient database error")
+	fs.IntVar(&c.DBBreakerThreshold, "db-breaker-threshold", c.DBBreakerThreshold, "consecutive database failures that open the circuit breaker, 0 disables")
+	fs.DurationVar((*time.Duration)(&c.DBBreakerCooldown), "db-breaker-cooldown", time.Duration(c.DBBreakerCooldown), "how long the open circuit breaker rejects queries")
+	fs.Var(&c.DatabaseReplicaDSNs, "db-replicas", "comma-separated read replica dsns")
+	fs.DurationVar((*time.Duration)(&c.DBReplicaCheckInterval), "db-replica-check-interval", time.Duration(c.DBReplicaCheckInterval), "read replica health check interval")
+	fs.Var(&c.DatabaseShardDSNs, "db-shards", "comma-separated shard dsns, the database dsn then holds the global dedup index")
+	fs.IntVar(&c.DBPreviousShards, "db-previous-shards", c.DBPreviousShards, "number of shards before the last ones were added, 0 when not rebalancing")
+	fs.DurationVar((*time.Duration)(&c.DBReadYourWritesWindow), "db-read-your-writes-window", time.Duration(c.DBReadYourWritesWindow), "how long a written link is read from the primary")
+	fs.IntVar(&c.MinLength, "min-id-length", c.MinLength, "minimal short id length")
+	fs.IntVar(&c.MaxLength, "max-id-length", c.MaxLength, "maximal short id length")
+	fs.BoolVar(&c.EnableHTTPS, "s", c.EnableHTTPS, "enable https")
+	fs.StringVar(&c.TLSCertFile, "tls-cert", c.TLSCertFile, "tls certificate file")
+	fs.StringVar(&c.TLSKeyFile, "tls-key", c.TLSKeyFile, "tls key file")
+	fs.StringVar(&c.TLSCacheDir, "tls-cache-dir", c.TLSCacheDir, "self-signed certificate cache dir")
+	fs.StringVar(&c.HTTPRedirectAddress, "http-redirect", c.HTTPRedirectAddress, "http to https redirect listener address")
+	fs.DurationVar((*time.Duration)(&c.HSTSMaxAge), "hsts-max-age", time.Duration(c.HSTSMaxAge), "Strict-Transport-Security max-age, 0 disables")
+	fs.StringVar(&c.GRPCAddress, "g", c.GRPCAddress, "grpc server address")
+	fs.StringVar(&c.GRPCAuthToken, "grpc-auth-token", c.GRPCAuthToken, "grpc bearer token, empty disables auth")
+	fs.StringVar(&c.OTLPEndpoint, "otlp-endpoint", c.OTLPEndpoint, "OTLP/HTTP trace collector host:port, empty disables export")
+	fs.BoolVar(&c.OTLPInsecure, "otlp-insecure", c.OTLPInsecure, "send traces over plain http")
+	fs.Float64Var(&c.TraceSampleRatio, "trace-sample-ratio", c.TraceSampleRatio, "fraction of sampled root traces")
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "log level")
+	fs.StringVar(&c.LogFormat, "log-format", c.LogFormat, "log format: json or console")
+	fs.StringVar(&c.LogBodyMode, "log-body", c.LogBodyMode, "request body logging: off, truncated or redacted")
+	fs.IntVar(&c.LogBodyMaxBytes, "log-body-max-bytes", c.LogBodyMaxBytes, "max logged request body bytes")
+	fs.Var(&c.LogRedactQueryParams, "log-redact-query", "comma-separated query params redacted in logged bodies")
+	fs.Var(&c.LogRedactJSONFields, "log-redact-json", "comma-separated json fields redacted in logged bodies")
+	fs.IntVar(&c.LogSampleInitial, "log-sample-initial", c.LogSampleInitial, "redirect logs written per second before sampling, 0 disables sampling")
+	fs.IntVar(&c.LogSampleThereafter, "log-sample-thereafter", c.LogSampleThereafter, "after the initial ones, every n-th redirect log is written")
+	fs.IntVar(&c.CompressMinSize, "compress-min-size", c.CompressMinSize, "minimal response size to compress")
+	fs.Int64Var(&c.MaxDecompressedSize, "max-decompressed-size", c.MaxDecompressedSize, "maximal decompressed request body size")
+	fs.Int64Var(&c.MaxBodySize, "max-body-size", c.MaxBodySize, "maximal body size for single url requests")
+	fs.Int64Var(&c.MaxBatchBodySize, "max-batch-body-size", c.MaxBatchBodySize, "maximal body size for batch requests")
+	fs.IntVar(&c.BatchChunkSize, "batch-chunk-size", c.BatchChunkSize, "records persisted per storage call in batch requests")
+	fs.BoolVar(&c.SwaggerUI, "swagger-ui", c.SwaggerUI, "serve swagger ui at /api/docs")
+	fs.IntVar(&c.RedirectCode, "redirect-code", c.RedirectCode, "default redirect status: 301, 302, 307 or 308")
+	fs.DurationVar((*time.Duration)(&c.RedirectCacheMaxAge), "redirect-cache-max-age", time.Duration(c.RedirectCacheMaxAge), "default redirect cache lifetime, 0 omits cache headers, negative sends no-store")
+	fs.StringVar(&c.ReferrerPolicy, "referrer-policy", c.ReferrerPolicy, "default Referrer-Policy header for redirects")
+	fs.StringVar(&c.QueryPassthrough, "query-passthrough", c.QueryPassthrough, "default query forwarding on redirect: ignore, append, override or allowlist")
+	fs.Var(&c.QueryAllowlist, "query-allowlist", "comma-separated query params forwarded in allowlist mode")
+	fs.StringVar(&c.GeoIPDatabase, "geoip-db", c.GeoIPDatabase, "MaxMind country database for redirect rules")
+}
+
+// Load собирает конфигурацию в порядке приоритета:
+// значения по умолчанию < файл (-c / CONFIG) < переменные окружения < флаги.
+// Если передан флаг --print-config, конфигурация возвращается вместе с ErrPrintConfig
+// без проверки: ее печатают и с ошибками, а Validate вызывающий выполняет сам.
+func Load(name string, args []string) (Config, error) {
+	cfg := Default()
+
+	var fromFlags Config
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fromFlags.bindFlags(fs)
+
+	var configPath string
+	fs.StringVar(&configPath, "c", "", "config file (json or yaml)")
+	fs.StringVar(&configPath, "config", "", "config file (json or yaml)")
+	printConfig := fs.Bool("print-config", false, "print effective config and exit")
+
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if configPath == "" {
+		configPath = os.Getenv("CONFIG")
+	}
+
+	var err error
+	if configPath != "" {
+		err = cleanenv.ReadConfig(configPath, &cfg)
+	} else {
+		err = cleanenv.ReadEnv(&cfg)
 	}
-	if e.DatabaseInfo != "" {
-		DatabaseInfo = e.DatabaseInfo
+	if err != nil {
+		return cfg, fmt.Errorf("config error: %w", err)
+	}
+
+	apply := flag.NewFlagSet(name, flag.ContinueOnError)
+	cfg.bindFlags(apply)
+	fs.Visit(func(f *flag.Flag) {
+		if apply.Lookup(f.Name) != nil {
+			apply.Set(f.Name, f.Value.String())
+		}
+	})
+
+	// при включенном TLS базовый адрес по умолчанию тоже должен быть https
+	if cfg.EnableHTTPS && cfg.BaseURL == Default().BaseURL {
+		if u, err := url.Parse(cfg.BaseURL); err == nil {
+			u.Scheme = "https"
+			cfg.BaseURL = u.String()
+		}
+	}
+
+	if *printConfig {
+		return cfg, ErrPrintConfig
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+// Validate проверяет все поля и возвращает ошибку, перечисляющую каждое некорректное.
+func (c Config) Validate() error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(c.ServerAddress); err != nil {
+		errs = append(errs, fmt.Errorf("server_address: %w", err))
+	}
+
+	if u, err := url.Parse(c.BaseURL); err != nil {
+		errs = append(errs, fmt.Errorf("base_url: %w", err))
+	} else if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("base_url: %q must be an absolute http(s) url", c.BaseURL))
 	}
+
+	if c.MinLength < 1 {
+		errs = append(errs, fmt.Errorf("min_id_length: must be positive, got %d", c.MinLength))
+	}
+	if c.MaxLength <= c.MinLength {
+		errs = append(errs, fmt.Errorf("max_id_length: must be greater than min_id_length (%d), got %d", c.MinLength, c.MaxLength))
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, errors.New("tls_cert_file, tls_key_file: must be set together"))
+	}
+	if c.EnableHTTPS && c.TLSCertFile == "" && c.TLSCacheDir == "" {
+		errs = append(errs, errors.New("tls_cache_dir: required for self-signed certificate"))
+	}
+	if c.HTTPRedirectAddress != "" {
+		if _, _, err := net.SplitHostPort(c.HTTPRedirectAddress); err != nil {
+			errs = append(errs, fmt.Errorf("http_redirect_address: %w", err))
+		}
+	}
+	if c.GRPCAddress != "" {
+		if _, _, err := net.SplitHostPort(c.GRPCAddress); err != nil {
+			errs = append(errs, fmt.Errorf("grpc_address: %w", err))
+		}
+	}
+	if c.DatabaseDriver != "stdlib" && c.DatabaseDriver != "pgxpool" {
+		errs = append(errs, fmt.Errorf("database_driver: must be stdlib or pgxpool, got %q", c.DatabaseDriver))
+	}
+	if c.DBMaxOpenConns < 0 || c.DBMaxIdleConns < 0 {
+		errs = append(errs, errors.New("db_max_open_conns, db_max_idle_conns: must not be negative"))
+	}
+	if c.DBConnMaxLifetime < 0 || c.DBConnMaxIdleTime < 0 || c.DBConnectBackoff < 0 || c.DBMigrationLockTimeout < 0 || c.DBQueryTimeout < 0 || c.DBBreakerCooldown < 0 {
+		errs = append(errs, errors.New("db_conn_max_lifetime, db_conn_max_idle_time, db_connect_backoff, db_migration_lock_timeout, db_query_timeout, db_breaker_cooldown: must not be negative"))
+	}
+	if c.DBConnectAttempts < 1 {
+		errs = append(errs, fmt.Errorf("db_connect_attempts: must be positive, got %d", c.DBConnectAttempts))
+	}
+	if c.DBQueryRetries < 0 || c.DBBreakerThreshold < 0 {
+		errs = append(errs, errors.New("db_query_retries, db_breaker_threshold: must not be negative"))
+	}
+	if len(c.DatabaseReplicaDSNs) > 0 && c.DatabaseDriver != "stdlib" {
+		errs = append(errs, fmt.Errorf("database_replica_dsns: supported only by the stdlib driver, got %q", c.DatabaseDriver))
+	}
+	if strings.HasPrefix(c.DatabaseDSN, sqliteScheme) && (len(c.DatabaseReplicaDSNs) > 0 || len(c.DatabaseShardDSNs) > 0 || c.DatabaseDriver != "stdlib") {
+		errs = append(errs, errors.New("database_dsn: sqlite does not support replicas, shards or the pgxpool driver"))
+	}
+	if len(c.DatabaseShardDSNs) > 0 {
+		if c.DatabaseDSN == "" {
+			errs = append(errs, errors.New("database_dsn: required for the dedup index of database_shard_dsns"))
+		}
+		if c.DatabaseDriver != "stdlib" || len(c.DatabaseReplicaDSNs) > 0 {
+			errs = append(errs, errors.New("database_shard_dsns: supported only by the stdlib driver without replicas"))
+		}
+	}
+	if c.DBPreviousShards < 0 || c.DBPreviousShards > len(c.DatabaseShardDSNs) {
+		errs = append(errs, fmt.Errorf("db_previous_shards: must be within [0, %d], got %d", len(c.DatabaseShardDSNs), c.DBPreviousShards))
+	}
+	if c.DBReplicaCheckInterval <= 0 {
+		errs = append(errs, fmt.Errorf("db_replica_check_interval: must be positive, got %v", c.DBReplicaCheckInterval))
+	}
+	if c.DBReadYourWritesWindow < 0 {
+		errs = append(errs, fmt.Errorf("db_read_your_writes_window: must not be negative, got %v", c.DBReadYourWritesWindow))
+	}
+	if c.TraceSampleRatio < 0 || c.TraceSampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("trace_sample_ratio: must be within [0, 1], got %v", c.TraceSampleRatio))
+	}
+	if _, err := zapcore.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("log_level: %w", err))
+	}
+	if c.LogFormat != "json" && c.LogFormat != "console" {
+		errs = append(errs, fmt.Errorf("log_format: must be json or console, got %q", c.LogFormat))
+	}
+	if c.LogBodyMode != "off" && c.LogBodyMode != "truncated" && c.LogBodyMode != "redacted" {
+		errs = append(errs, fmt.Errorf("log_body_mode: must be off, truncated or redacted, got %q", c.LogBodyMode))
+	}
+	if c.LogBodyMaxBytes < 0 {
+		errs = append(errs, fmt.Errorf("log_body_max_bytes: must not be negative, got %d", c.LogBodyMaxBytes))
+	}
+	if c.LogSampleInitial < 0 || c.LogSampleThereafter < 0 {
+		errs = append(errs, errors.New("log_sample_initial, log_sample_thereafter: must not be negative"))
+	}
+	if c.CompressMinSize < 0 {
+		errs = append(errs, fmt.Errorf("compress_min_size: must not be negative, got %d", c.CompressMinSize))
+	}
+	if c.MaxDecompressedSize <= 0 {
+		errs = append(errs, fmt.Errorf("max_decompressed_size: must be positive, got %d", c.MaxDecompressedSize))
+	}
+	if c.MaxBodySize <= 0 {
+		errs = append(errs, fmt.Errorf("max_body_size: must be positive, got %d", c.MaxBodySize))
+	}
+	if c.MaxBatchBodySize <= 0 {
+		errs = append(errs, fmt.Errorf("max_batch_body_size: must be positive, got %d", c.MaxBatchBodySize))
+	}
+	if c.BatchChunkSize <= 0 {
+		errs = append(errs, fmt.Errorf("batch_chunk_size: must be positive, got %d", c.BatchChunkSize))
+	}
+	if c.HSTSMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("hsts_max_age: must not be negative, got %s", c.HSTSMaxAge))
+	}
+	if c.RedirectCode == 0 {
+		errs = append(errs, errors.New("redirect_code: required"))
+	}
+	if err := c.RedirectPolicy().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("redirect_code, referrer_policy, query_passthrough: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// RedirectPolicy возвращает глобальные параметры редиректа.
+func (c Config) RedirectPolicy() entities.RedirectPolicy {
+	maxAge := int(time.Duration(c.RedirectCacheMaxAge) / time.Second)
+	if c.RedirectCacheMaxAge < 0 {
+		maxAge = -1
+	}
+
+	return entities.RedirectPolicy{
+		Code:           c.RedirectCode,
+		CacheMaxAge:    entities.MaxAge(maxAge),
+		ReferrerPolicy: c.ReferrerPolicy,
+		QueryMode:      entities.QueryMode(c.QueryPassthrough),
+		QueryAllowlist: c.QueryAllowlist,
+	}
+}
+
+var dsnPasswordRe = regexp.MustCompile(`(password\s*=\s*)('[^']*'|\S+)`)
+
+// redactDSNs возвращает копию списка, не трогая исходный.
+func redactDSNs(dsns StringList) StringList {
+	if len(dsns) == 0 {
+		return dsns
+	}
+
+	result := make(StringList, len(dsns))
+	for i, dsn := range dsns {
+		result[i] = redactDSN(dsn)
+	}
+	return result
+}
+
+func redactDSN(dsn string) string {
+	if dsn == "" {
+		return ""
+	}
+
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+		return u.String()
+	}
+
+	return dsnPasswordRe.ReplaceAllString(dsn, "${1}"+redacted)
+}
+
+// Redacted возвращает копию конфигурации без секретов.
+func (c Config) Redacted() Config {
+	c.DatabaseDSN = redactDSN(c.DatabaseDSN)
+	c.DatabaseReplicaDSNs = redactDSNs(c.DatabaseReplicaDSNs)
+	c.DatabaseShardDSNs = redactDSNs(c.DatabaseShardDSNs)
+	if c.GRPCAuthToken != "" {
+		c.GRPCAuthToken = redacted
+	}
+	return c
+}
+
+// Dump возвращает действующую конфигурацию в JSON со скрытыми секретами.
+func (c Config) Dump() ([]byte, error) {
+	return json.MarshalIndent(c.Redacted(), "", "  ")
 }
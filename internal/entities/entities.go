@@ -1,6 +1,50 @@
 package entities
 
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unicode"
+)
+
+// максимальная длина области дедупликации
+const MaxScopeLength = 128
+
+var ErrInvalidScope = errors.New("invalid dedupe scope")
+
 type URLRecord struct {
 	OriginalURL string
 	Short       string
+	CreatedAt   time.Time
+	Redirect    RedirectPolicy
+	Rules       []Rule
+	// адреса A/B-теста; пустой список — все переходы ведут на OriginalURL
+	Variants []Variant
+	// Scope — область дедупликации (пользователь или команда), пустая — общая.
+	Scope string
+	// Dedupe — запись участвует в индексе original→short своей области:
+	// повторное сокращение того же адреса вернет ее вместо новой.
+	Dedupe bool
+}
+
+// DedupeKey — ключ индекса original→short.
+type DedupeKey struct {
+	Scope       string
+	OriginalURL string
+}
+
+func (r URLRecord) DedupeKey() DedupeKey {
+	return DedupeKey{Scope: r.Scope, OriginalURL: r.OriginalURL}
+}
+
+func ValidateScope(scope string) error {
+	if len(scope) > MaxScopeLength {
+		return fmt.Errorf("%w: at most %d bytes allowed, got %d", ErrInvalidScope, MaxScopeLength, len(scope))
+	}
+	for _, r := range scope {
+		if !unicode.IsPrint(r) {
+			return fmt.Errorf("%w: %q contains non-printable characters", ErrInvalidScope, scope)
+		}
+	}
+	return nil
 }
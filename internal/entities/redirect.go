@@ -0,0 +1,118 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var ErrInvalidRedirectPolicy = errors.New("invalid redirect policy")
+
+// допустимые значения заголовка Referrer-Policy
+var referrerPolicies = map[string]bool{
+	"no-referrer":                     true,
+	"no-referrer-when-downgrade":      true,
+	"origin":                          true,
+	"origin-when-cross-origin":        true,
+	"same-origin":                     true,
+	"strict-origin":                   true,
+	"strict-origin-when-cross-origin": true,
+	"unsafe-url":                      true,
+}
+
+// QueryMode задает, как параметры запроса к короткой ссылке переносятся в адрес назначения.
+type QueryMode string
+
+const (
+	// параметры запроса отбрасываются
+	QueryIgnore QueryMode = "ignore"
+	// параметры добавляются к параметрам адреса назначения
+	QueryAppend QueryMode = "append"
+	// параметры заменяют одноименные параметры адреса назначения
+	QueryOverride QueryMode = "override"
+	// как override, но переносятся только параметры из QueryAllowlist
+	QueryAllowlist QueryMode = "allowlist"
+)
+
+// RedirectPolicy описывает ответ на переход по ссылке.
+// Нулевое значение поля означает, что действует глобальная настройка.
+type RedirectPolicy struct {
+	// 301, 302, 307 или 308
+	Code int
+	// время кеширования редиректа в секундах: 0 — без заголовков кеширования,
+	// отрицательное запрещает кеширование; nil — действует глобальная настройка
+	CacheMaxAge *int
+	// значение заголовка Referrer-Policy
+	ReferrerPolicy string
+
+	QueryMode      QueryMode
+	QueryAllowlist []string
+	// параметры (обычно utm_*), которые добавляются, если их нет ни в адресе, ни в запросе
+	DefaultParams map[string]string
+
+	// префиксная ссылка: /{id}/rest перенаправляет на исходный URL с дописанным rest
+	Prefix bool
+}
+
+// MaxAge возвращает значение RedirectPolicy.CacheMaxAge, заданное явно.
+func MaxAge(seconds int) *int {
+	return &seconds
+}
+
+func (p RedirectPolicy) Validate() error {
+	switch p.Code {
+	case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return fmt.Errorf("%w: redirect code must be one of 301, 302, 307, 308, got %d", ErrInvalidRedirectPolicy, p.Code)
+	}
+
+	if p.ReferrerPolicy != "" && !referrerPolicies[p.ReferrerPolicy] {
+		return fmt.Errorf("%w: unknown referrer policy %q", ErrInvalidRedirectPolicy, p.ReferrerPolicy)
+	}
+
+	switch p.QueryMode {
+	case "", QueryIgnore, QueryAppend, QueryOverride, QueryAllowlist:
+	default:
+		return fmt.Errorf("%w: query mode must be one of ignore, append, override, allowlist, got %q", ErrInvalidRedirectPolicy, p.QueryMode)
+	}
+	for _, name := range p.QueryAllowlist {
+		if name == "" {
+			return fmt.Errorf("%w: empty name in query allowlist", ErrInvalidRedirectPolicy)
+		}
+		// имена сравниваются точно, шаблон вроде utm_* не совпал бы ни с одним параметром
+		if strings.Contains(name, "*") {
+			return fmt.Errorf("%w: query allowlist takes exact names, got pattern %q", ErrInvalidRedirectPolicy, name)
+		}
+	}
+	for name := range p.DefaultParams {
+		if name == "" {
+			return fmt.Errorf("%w: empty default parameter name", ErrInvalidRedirectPolicy)
+		}
+	}
+
+	return nil
+}
+
+// WithDefaults заполняет незаданные поля значениями из defaults.
+func (p RedirectPolicy) WithDefaults(defaults RedirectPolicy) RedirectPolicy {
+	if p.Code == 0 {
+		p.Code = defaults.Code
+	}
+	if p.CacheMaxAge == nil {
+		p.CacheMaxAge = defaults.CacheMaxAge
+	}
+	if p.ReferrerPolicy == "" {
+		p.ReferrerPolicy = defaults.ReferrerPolicy
+	}
+	if p.QueryMode == "" {
+		p.QueryMode = defaults.QueryMode
+	}
+	if len(p.QueryAllowlist) == 0 {
+		p.QueryAllowlist = defaults.QueryAllowlist
+	}
+	if len(p.DefaultParams) == 0 {
+		p.DefaultParams = defaults.DefaultParams
+	}
+	return p
+}
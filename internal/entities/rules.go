@@ -0,0 +1,23 @@
+package entities
+
+import "time"
+
+// Rule — условный редирект: запрос, удовлетворяющий всем условиям, уходит на Target.
+// Правила ссылки проверяются по порядку, срабатывает первое подходящее.
+type Rule struct {
+	Conditions RuleConditions `json:"when"`
+	Target     string         `json:"target"`
+}
+
+// RuleConditions — условия правила. Пустое условие выполняется для любого запроса.
+type RuleConditions struct {
+	// классы устройств: ios, android, mobile, desktop, bot
+	Devices []string `json:"device,omitempty"`
+	// языковые диапазоны Accept-Language, например en или pt-BR
+	Languages []string `json:"language,omitempty"`
+	// коды стран ISO 3166-1 alpha-2
+	Countries []string `json:"country,omitempty"`
+	// окно времени [From, Until)
+	From  *time.Time `json:"from,omitempty"`
+	Until *time.Time `json:"until,omitempty"`
+}
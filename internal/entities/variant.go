@@ -0,0 +1,52 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// максимальное число вариантов у одной ссылки
+const MaxVariants = 20
+
+var ErrInvalidVariant = errors.New("invalid variant")
+
+// имя варианта попадает в cookie, поэтому набор символов ограничен
+var variantNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Variant — один из адресов назначения A/B-ссылки.
+type Variant struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+	// сколько переходов пришлось на вариант
+	Clicks int64 `json:"clicks"`
+}
+
+func ValidateVariants(variants []Variant) error {
+	if len(variants) > MaxVariants {
+		return fmt.Errorf("%w: at most %d variants allowed, got %d", ErrInvalidVariant, MaxVariants, len(variants))
+	}
+
+	names := make(map[string]bool, len(variants))
+	for _, v := range variants {
+		if !variantNameRe.MatchString(v.Name) {
+			return fmt.Errorf("%w: name %q must be 1-32 latin letters, digits, '-' or '_'", ErrInvalidVariant, v.Name)
+		}
+		if names[v.Name] {
+			return fmt.Errorf("%w: duplicate name %q", ErrInvalidVariant, v.Name)
+		}
+		names[v.Name] = true
+
+		u, err := url.Parse(v.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: %s: url %q must be an absolute http(s) url", ErrInvalidVariant, v.Name, v.URL)
+		}
+		if v.Weight < 1 {
+			return fmt.Errorf("%w: %s: weight must be positive, got %d", ErrInvalidVariant, v.Name, v.Weight)
+		}
+	}
+
+	return nil
+}
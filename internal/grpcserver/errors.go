@@ -0,0 +1,59 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/repository"
+	"github.com/Oleg2210/goshortener/internal/rules"
+	"github.com/Oleg2210/goshortener/internal/service"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errInvalidBatchItem = errors.New("correlation_id and original_url are required")
+
+// errorCode классифицирует ошибку так же, как classifyError в HTTP-хэндлерах.
+func errorCode(err error) codes.Code {
+	var pgErr *pgconn.PgError
+
+	switch {
+	case errors.Is(err, errInvalidBatchItem), errors.Is(err, entities.ErrInvalidRedirectPolicy), errors.Is(err, rules.ErrInvalidRule),
+		errors.Is(err, entities.ErrInvalidVariant), errors.Is(err, entities.ErrInvalidScope):
+		return codes.InvalidArgument
+	case errors.Is(err, service.ErrIDDoesNotExists), errors.Is(err, repository.ErrNotFound):
+		return codes.NotFound
+	case errors.Is(err, service.ErrURLExists), errors.Is(err, repository.ErrAlreadyExists), errors.Is(err, repository.ErrOriginalExists):
+		return codes.AlreadyExists
+	case errors.Is(err, service.ErrOutOfCombinations):
+		return codes.ResourceExhausted
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, repository.ErrUnavailable), errors.As(err, &pgErr), pgconn.SafeToRetry(err), pgconn.Timeout(err):
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
+
+// statusError переводит ошибку сервиса в gRPC-статус. Детали внутренних ошибок
+// и отказов хранилища наружу не отдаются, а пишутся в лог.
+func (s *Server) statusError(err error) error {
+	code := errorCode(err)
+
+	switch code {
+	case codes.Unavailable:
+		s.Logger.Error("storage is unavailable", zap.Error(err))
+		return status.Error(code, "storage is unavailable")
+	case codes.Internal:
+		s.Logger.Error("request failed", zap.Error(err))
+		return status.Error(code, "internal error")
+	default:
+		return status.Error(code, err.Error())
+	}
+}
@@ -0,0 +1,116 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/service"
+	pb "github.com/Oleg2210/goshortener/pkg/shortenerpb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
+)
+
+// Server реализует gRPC-контракт поверх того же ShortenerService, что и HTTP-хэндлеры.
+type Server struct {
+	pb.UnimplementedShortenerServer
+
+	ShortenerService *service.ShortenerService
+	Logger           *zap.Logger
+	BaseURL          string
+}
+
+// New создает grpc.Server с интерцепторами логирования, восстановления после паники
+// и проверки авторизационных метаданных. Пустой authToken отключает проверку.
+func New(srv *Server, authToken string) *grpc.Server {
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			RecoveryInterceptor(srv.Logger),
+			LoggingInterceptor(srv.Logger),
+			AuthInterceptor(authToken),
+		),
+	)
+	pb.RegisterShortenerServer(s, srv)
+
+	return s
+}
+
+func (s *Server) Shorten(ctx context.Context, req *pb.ShortenRequest) (*pb.ShortenResponse, error) {
+	var alreadyExists bool
+
+	id, err := s.ShortenerService.Shorten(ctx, req.GetUrl())
+	if err != nil {
+		if !errors.Is(err, service.ErrURLExists) {
+			return nil, s.statusError(err)
+		}
+		alreadyExists = true
+	}
+
+	resultURL, err := url.JoinPath(s.BaseURL, id)
+	if err != nil {
+		s.Logger.Error("error while url join", zap.Error(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.ShortenResponse{Result: resultURL, AlreadyExists: alreadyExists}, nil
+}
+
+func (s *Server) BatchShorten(ctx context.Context, req *pb.BatchShortenRequest) (*pb.BatchShortenResponse, error) {
+	records := make([]entities.URLRecord, 0, len(req.GetItems()))
+	for _, item := range req.GetItems() {
+		if item.GetOriginalUrl() == "" || item.GetCorrelationId() == "" {
+			return nil, s.statusError(errInvalidBatchItem)
+		}
+		records = append(
+			records,
+			// как и в HTTP, адрес по умолчанию участвует в дедупликации общей области
+			entities.URLRecord{
+				OriginalURL: item.GetOriginalUrl(),
+				Short:       item.GetCorrelationId(),
+				Dedupe:      true,
+			},
+		)
+	}
+
+	shorts, err := s.ShortenerService.BatchShorten(ctx, records)
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+
+	resp := &pb.BatchShortenResponse{Items: make([]*pb.BatchShortenResponseItem, 0, len(records))}
+	for i, r := range records {
+		resultURL, err := url.JoinPath(s.BaseURL, shorts[i])
+		if err != nil {
+			s.Logger.Error("error while url join", zap.Error(err))
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+
+		resp.Items = append(resp.Items, &pb.BatchShortenResponseItem{
+			CorrelationId: r.Short,
+			ShortUrl:      resultURL,
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *Server) GetURL(ctx context.Context, req *pb.GetURLRequest) (*pb.GetURLResponse, error) {
+	original, err := s.ShortenerService.GetURL(ctx, req.GetId())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+
+	return &pb.GetURLResponse{OriginalUrl: original}, nil
+}
+
+func (s *Server) Ping(ctx context.Context, _ *pb.PingRequest) (*pb.PingResponse, error) {
+	if !s.ShortenerService.Ping(ctx) {
+		return nil, status.Error(codes.Unavailable, "storage is unavailable")
+	}
+
+	return &pb.PingResponse{}, nil
+}
@@ -0,0 +1,219 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/Oleg2210/goshortener/internal/config"
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/repository"
+	"github.com/Oleg2210/goshortener/internal/service"
+	pb "github.com/Oleg2210/goshortener/pkg/shortenerpb"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newClient(t *testing.T, token string) pb.ShortenerClient {
+	return newClientWithRepo(t, token, repository.NewMemoryRepository())
+}
+
+func newClientWithRepo(t *testing.T, token string, repo repository.URLRepository) pb.ShortenerClient {
+	cfg := config.Default()
+	shortenerService := service.NewShortenerService(
+		repo,
+		cfg.MinLength,
+		cfg.MaxLength,
+	)
+
+	listener := bufconn.Listen(1 << 20)
+	s := New(&Server{
+		ShortenerService: shortenerService,
+		Logger:           zap.NewNop(),
+		BaseURL:          cfg.BaseURL,
+	}, token)
+	go s.Serve(listener)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewShortenerClient(conn)
+}
+
+func TestShortenAndGetURL(t *testing.T) {
+	client := newClient(t, "")
+	ctx := context.Background()
+
+	resp, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "https://yandex.kz"})
+	require.NoError(t, err)
+	assert.False(t, resp.GetAlreadyExists())
+
+	id := resp.GetResult()[strings.LastIndex(resp.GetResult(), "/")+1:]
+	got, err := client.GetURL(ctx, &pb.GetURLRequest{Id: id})
+	require.NoError(t, err)
+	assert.Equal(t, "https://yandex.kz", got.GetOriginalUrl())
+
+	_, err = client.GetURL(ctx, &pb.GetURLRequest{Id: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	client := newClient(t, "secret")
+
+	_, err := client.Shorten(context.Background(), &pb.ShortenRequest{Url: "https://yandex.kz"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "https://yandex.kz"})
+	assert.NoError(t, err)
+}
+
+func TestBatchShorten(t *testing.T) {
+	client := newClient(t, "")
+	ctx := context.Background()
+
+	resp, err := client.BatchShorten(ctx, &pb.BatchShortenRequest{Items: []*pb.BatchShortenRequestItem{
+		{CorrelationId: "a1", OriginalUrl: "https://example.com/a"},
+		{CorrelationId: "b1", OriginalUrl: "https://example.com/b"},
+	}})
+	require.NoError(t, err)
+	require.Len(t, resp.GetItems(), 2)
+	assert.Equal(t, "a1", resp.GetItems()[0].GetCorrelationId())
+	assert.True(t, strings.HasSuffix(resp.GetItems()[0].GetShortUrl(), "/a1"))
+
+	got, err := client.GetURL(ctx, &pb.GetURLRequest{Id: "b1"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/b", got.GetOriginalUrl())
+
+	_, err = client.BatchShorten(ctx, &pb.BatchShortenRequest{Items: []*pb.BatchShortenRequestItem{
+		{CorrelationId: "a1", OriginalUrl: "https://example.com/c"},
+	}})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	// уже сокращенный адрес не дублируется: ответ ведет на существующую ссылку
+	resp, err = client.BatchShorten(ctx, &pb.BatchShortenRequest{Items: []*pb.BatchShortenRequestItem{
+		{CorrelationId: "c1", OriginalUrl: "https://example.com/b"},
+		{CorrelationId: "d1", OriginalUrl: "https://example.com/d"},
+	}})
+	require.NoError(t, err)
+	require.Len(t, resp.GetItems(), 2)
+	assert.Equal(t, "c1", resp.GetItems()[0].GetCorrelationId())
+	assert.True(t, strings.HasSuffix(resp.GetItems()[0].GetShortUrl(), "/b1"))
+	assert.True(t, strings.HasSuffix(resp.GetItems()[1].GetShortUrl(), "/d1"))
+	_, err = client.GetURL(ctx, &pb.GetURLRequest{Id: "c1"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	short, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com/a"})
+	require.NoError(t, err)
+	assert.True(t, short.GetAlreadyExists())
+	assert.True(t, strings.HasSuffix(short.GetResult(), "/a1"))
+
+	_, err = client.BatchShorten(ctx, &pb.BatchShortenRequest{Items: []*pb.BatchShortenRequestItem{
+		{CorrelationId: "", OriginalUrl: "https://example.com/d"},
+	}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestPing(t *testing.T) {
+	_, err := newClient(t, "").Ping(context.Background(), &pb.PingRequest{})
+	assert.NoError(t, err)
+
+	_, err = newClientWithRepo(t, "", &failingRepository{err: repository.ErrUnavailable}).Ping(context.Background(), &pb.PingRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+// failingRepository отвечает err на любую операцию.
+type failingRepository struct {
+	repository.MemoryRepository
+	err error
+}
+
+func (r *failingRepository) Save(context.Context, entities.URLRecord) (string, error) {
+	return "", r.err
+}
+
+func (r *failingRepository) BatchSave(context.Context, []entities.URLRecord) ([]string, error) {
+	return nil, r.err
+}
+
+func (r *failingRepository) GetRecord(context.Context, string) (entities.URLRecord, error) {
+	return entities.URLRecord{}, r.err
+}
+
+func (r *failingRepository) Ping(context.Context) bool {
+	return false
+}
+
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{repository.ErrUnavailable, codes.Unavailable},
+		{fmt.Errorf("query: %w", &pgconn.PgError{Code: "57P01"}), codes.Unavailable},
+		{context.Canceled, codes.Canceled},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+		{repository.ErrOriginalExists, codes.AlreadyExists},
+		{entities.ErrInvalidScope, codes.InvalidArgument},
+		{fmt.Errorf("boom"), codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			client := newClientWithRepo(t, "", &failingRepository{err: tt.err})
+			ctx := context.Background()
+
+			_, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com"})
+			assert.Equal(t, tt.code, status.Code(err), "Shorten")
+
+			_, err = client.BatchShorten(ctx, &pb.BatchShortenRequest{Items: []*pb.BatchShortenRequestItem{
+				{CorrelationId: "a1", OriginalUrl: "https://example.com"},
+			}})
+			assert.Equal(t, tt.code, status.Code(err), "BatchShorten")
+
+			_, err = client.GetURL(ctx, &pb.GetURLRequest{Id: "a1"})
+			assert.Equal(t, tt.code, status.Code(err), "GetURL")
+		})
+	}
+
+	t.Run("internal details are hidden", func(t *testing.T) {
+		client := newClientWithRepo(t, "", &failingRepository{err: fmt.Errorf("dial tcp 10.0.0.1: secret")})
+
+		_, err := client.GetURL(context.Background(), &pb.GetURLRequest{Id: "a1"})
+		assert.NotContains(t, status.Convert(err).Message(), "secret")
+	})
+
+	t.Run("out of combinations", func(t *testing.T) {
+		client := newClientWithRepo(t, "", &failingRepository{err: repository.ErrAlreadyExists})
+
+		_, err := client.Shorten(context.Background(), &pb.ShortenRequest{Url: "https://example.com"})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		client := newClientWithRepo(t, "", &failingRepository{err: repository.ErrNotFound})
+
+		_, err := client.GetURL(context.Background(), &pb.GetURLRequest{Id: "a1"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
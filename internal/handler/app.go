@@ -1,22 +1,37 @@
 package handler
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 
-	"github.com/Oleg2210/goshortener/internal/config"
 	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/rules"
 	"github.com/Oleg2210/goshortener/internal/serializers"
 	"github.com/Oleg2210/goshortener/internal/service"
+	"github.com/Oleg2210/goshortener/pkg/middleware/logging"
+	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
 
 type App struct {
 	ShortenerService *service.ShortenerService
 	Logger           *zap.Logger
+	BaseURL          string
+	// сколько записей батча сохраняется за одно обращение к хранилищу
+	BatchChunkSize int
+	// параметры редиректа для ссылок, у которых они не заданы
+	Redirect entities.RedirectPolicy
+	// источник страны для правил; nil — условия по стране не выполняются
+	Geo rules.GeoResolver
+}
+
+// log возвращает логгер, привязанный к трассе запроса.
+func (a *App) log(r *http.Request) *zap.Logger {
+	return a.Logger.With(logging.TraceFields(r.Context())...)
 }
 
 func (a *App) HandlePost(w http.ResponseWriter, r *http.Request) {
@@ -24,8 +39,7 @@ func (a *App) HandlePost(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 
 	if err != nil {
-		a.Logger.Error("failed to read request body", zap.Error(err))
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		a.plainError(w, r, bodyError(err))
 		return
 	}
 
@@ -37,144 +51,244 @@ func (a *App) HandlePost(w http.ResponseWriter, r *http.Request) {
 		if errors.Is(err, service.ErrURLExists) {
 			returnStatus = http.StatusConflict
 		} else {
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			a.plainError(w, r, err)
 			return
 		}
 	}
 
-	w.WriteHeader(returnStatus)
-
-	resolveURL, err := url.JoinPath(config.ResolveAddress, id)
+	resolveURL, err := url.JoinPath(a.BaseURL, id)
 	if err != nil {
-		a.Logger.Error("error while url join", zap.Error(err))
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		a.plainError(w, r, err)
 		return
 	}
 
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(returnStatus)
 	fmt.Fprint(w, resolveURL)
 }
 
-func (a *App) HandlePostJSON(w http.ResponseWriter, r *http.Request) {
+// dedupe возвращает флаг дедупликации запроса, по умолчанию включенный.
+func dedupe(o serializers.DedupeOptions) bool {
+	return o.Dedupe == nil || *o.Dedupe
+}
+
+// shortenJSON разбирает тело /shorten и сокращает URL. При ошибке ответ уже отправлен.
+func (a *App) shortenJSON(w http.ResponseWriter, r *http.Request) (string, int, bool) {
 	returnStatus := http.StatusCreated
 	body, err := io.ReadAll(r.Body)
 
 	if err != nil {
-		a.Logger.Error("failed to read request body", zap.Error(err))
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
-		return
+		a.problem(w, r, bodyError(err))
+		return "", 0, false
 	}
 
 	var req serializers.Request
 
 	if err := req.UnmarshalJSON(body); err != nil {
-		http.Error(w, "invalid json", http.StatusBadRequest)
-		return
+		a.problem(w, r, jsonError(err))
+		return "", 0, false
 	}
 
-	id, err := a.ShortenerService.Shorten(r.Context(), req.URL)
+	id, err := a.ShortenerService.ShortenRecord(r.Context(), entities.URLRecord{
+		OriginalURL: req.URL,
+		Redirect:    redirectPolicy(req.RedirectOptions),
+		Variants:    toVariants(req.Variants),
+		Scope:       req.Scope,
+		Dedupe:      dedupe(req.DedupeOptions),
+	})
 
 	if err != nil {
 		if errors.Is(err, service.ErrURLExists) {
 			returnStatus = http.StatusConflict
 		} else {
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
-			return
+			a.problem(w, r, err)
+			return "", 0, false
 		}
 	}
 
-	resultURL, err := url.JoinPath(config.ResolveAddress, id)
+	return id, returnStatus, true
+}
+
+// HandlePostJSON обслуживает /api/shorten и /api/v1/shorten. Для совместимости
+// с клиентами v1 неподдерживаемый Accept не приводит к 406, ответ отдается в JSON.
+func (a *App) HandlePostJSON(w http.ResponseWriter, r *http.Request) {
+	mediaType, err := negotiate(r.Header.Get("Accept"))
+	if err != nil {
+		mediaType = mediaJSON
+	}
+
+	id, returnStatus, ok := a.shortenJSON(w, r)
+	if !ok {
+		return
+	}
+
+	resultURL, err := url.JoinPath(a.BaseURL, id)
 
 	if err != nil {
-		a.Logger.Error("error while url join", zap.Error(err))
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		a.problem(w, r, err)
 		return
 	}
 
-	resp := serializers.Response{
+	a.respond(w, r, returnStatus, mediaType, serializers.Response{
 		Result: resultURL,
+	})
+}
+
+// HandlePostJSONV2 обслуживает /api/v2/shorten и отвечает объектом ссылки.
+func (a *App) HandlePostJSONV2(w http.ResponseWriter, r *http.Request) {
+	mediaType, err := negotiate(r.Header.Get("Accept"))
+	if err != nil {
+		a.problem(w, r, err)
+		return
 	}
-	jsonBytes, _ := resp.MarshalJSON()
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(returnStatus)
-	w.Write(jsonBytes)
+	id, returnStatus, ok := a.shortenJSON(w, r)
+	if !ok {
+		return
+	}
+
+	a.respondLink(w, r, returnStatus, mediaType, id)
 }
 
-func (a *App) HandlePostBatchJSON(w http.ResponseWriter, r *http.Request) {
-	body, err := io.ReadAll(r.Body)
+// HandleGetLink обслуживает /api/v2/urls/{id}.
+func (a *App) HandleGetLink(w http.ResponseWriter, r *http.Request) {
+	mediaType, err := negotiate(r.Header.Get("Accept"))
 	if err != nil {
-		a.Logger.Error("failed to read request body", zap.Error(err))
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		a.problem(w, r, err)
 		return
 	}
 
-	var reqItems serializers.BatchRequestItemSlice
-	if err := reqItems.UnmarshalJSON(body); err != nil {
-		http.Error(w, "invalid json", http.StatusBadRequest)
+	a.respondLink(w, r, http.StatusOK, mediaType, chi.URLParam(r, "id"))
+}
+
+func (a *App) respondLink(w http.ResponseWriter, r *http.Request, status int, mediaType string, id string) {
+	record, shortURL, ok := a.lookup(w, r, id)
+	if !ok {
 		return
 	}
 
-	records := make([]entities.URLRecord, 0, len(reqItems))
-	for _, r := range reqItems {
-		records = append(
-			records,
-			entities.URLRecord{
-				OriginalURL: r.OriginalURL,
-				Short:       r.CorrelationID,
-			},
-		)
+	a.respond(w, r, status, mediaType, serializers.Link{
+		ID:          record.Short,
+		ShortURL:    shortURL,
+		OriginalURL: record.OriginalURL,
+		CreatedAt:   record.CreatedAt,
+	})
+}
+
+// HandlePostBatchJSON разбирает массив потоково и сохраняет его частями по
+// BatchChunkSize записей, поэтому память не растет с размером батча.
+// Статус ответа фиксируется после сохранения первой части: если сбой случится
+// позже, соединение обрывается, чтобы клиент не принял неполный ответ за успех.
+// Сохраненные части при этом остаются, но повтор того же батча не конфликтует
+// с ними (см. URLRepository.BatchSave), так что клиент может просто повторить запрос.
+func (a *App) HandlePostBatchJSON(w http.ResponseWriter, r *http.Request) {
+	chunkSize := a.BatchChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultBatchChunkSize
 	}
 
-	err = a.ShortenerService.BatchShorten(r.Context(), records)
-	if err != nil {
-		a.Logger.Error("error in batch saving", zap.Error(err))
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	dec := json.NewDecoder(r.Body)
+	if err := expectDelim(dec, '['); err != nil {
+		a.problem(w, r, jsonError(err))
 		return
 	}
 
-	var respItems serializers.BatchResponseItemSlice
-	for _, r := range records {
-		resultURL, err := url.JoinPath(config.ResolveAddress, r.Short)
+	out := batchWriter{w: w}
+	chunk := make([]entities.URLRecord, 0, chunkSize)
+
+	flush := func() bool {
+		if len(chunk) == 0 {
+			return true
+		}
 
+		shorts, err := a.ShortenerService.BatchShorten(r.Context(), chunk)
 		if err != nil {
-			a.Logger.Error("error while url join", zap.Error(err))
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			a.failBatch(w, r, &out, err)
+			return false
+		}
+
+		for i, record := range chunk {
+			// для уже сокращенного адреса short_url ведет на существующую ссылку
+			resultURL, err := url.JoinPath(a.BaseURL, shorts[i])
+			if err != nil {
+				a.failBatch(w, r, &out, err)
+				return false
+			}
+
+			out.write(serializers.BatchResponseItem{
+				CorrelationID: record.Short,
+				ShortURL:      resultURL,
+			})
+		}
+
+		chunk = chunk[:0]
+		return true
+	}
+
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			a.failBatch(w, r, &out, jsonError(err))
 			return
 		}
 
-		response := serializers.BatchResponseItem{
-			CorrelationID: r.Short,
-			ShortURL:      resultURL,
+		var item serializers.BatchRequestItem
+		if err := item.UnmarshalJSON(raw); err != nil {
+			a.failBatch(w, r, &out, jsonError(err))
+			return
+		}
+		if item.CorrelationID == "" || item.OriginalURL == "" {
+			a.failBatch(w, r, &out, errInvalidBatchItem)
+			return
+		}
+
+		chunk = append(chunk, entities.URLRecord{
+			OriginalURL: item.OriginalURL,
+			Short:       item.CorrelationID,
+			Redirect:    redirectPolicy(item.RedirectOptions),
+			Variants:    toVariants(item.Variants),
+			Scope:       item.Scope,
+			Dedupe:      dedupe(item.DedupeOptions),
+		})
+
+		if len(chunk) == chunkSize && !flush() {
+			return
 		}
-		respItems = append(respItems, response)
 	}
 
-	jsonBytes, err := respItems.MarshalJSON()
-	if err != nil {
-		a.Logger.Error("error in resonse serializing", zap.Error(err))
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	if err := expectDelim(dec, ']'); err != nil {
+		a.failBatch(w, r, &out, jsonError(err))
+		return
+	}
+	if err := expectEOF(dec); err != nil {
+		a.failBatch(w, r, &out, jsonError(err))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	w.Write(jsonBytes)
+	if !flush() {
+		return
+	}
+	out.finish()
 }
 
 func (a *App) HandleGet(w http.ResponseWriter, r *http.Request) {
 	id := r.URL.Path[1:]
-	url, err := a.ShortenerService.GetURL(r.Context(), id)
+	if id, ok := isPreview(id); ok {
+		a.handlePreview(w, r, id)
+		return
+	}
+
+	record, err := a.ShortenerService.GetRecord(r.Context(), id)
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		a.problem(w, r, err)
 		return
 	}
-	w.Header().Set("Location", url)
-	w.WriteHeader(http.StatusTemporaryRedirect)
+	a.writeRedirect(w, r, a.resolve(w, r, record))
 }
 
 func (a *App) HandlePing(w http.ResponseWriter, r *http.Request) {
 	if pinged := a.ShortenerService.Ping(r.Context()); !pinged {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		a.problem(w, r, errStorageUnavailable)
 		return
 	}
 
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Oleg2210/goshortener/internal/serializers"
+	"go.uber.org/zap"
+)
+
+const defaultBatchChunkSize = 1000
+
+var errInvalidBatchItem = errors.New("correlation_id and original_url are required")
+
+func isTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
+
+// bodyError оборачивает ошибку чтения тела, сохраняя *http.MaxBytesError для ответа 413.
+func bodyError(err error) error {
+	if isTooLarge(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", errInvalidBody, err)
+}
+
+// jsonError оборачивает ошибку разбора JSON, сохраняя *http.MaxBytesError для ответа 413.
+func jsonError(err error) error {
+	if isTooLarge(err) || errors.Is(err, errInvalidBatchItem) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", errInvalidJSON, err)
+}
+
+// failBatch отвечает ошибкой, если ответ еще не начат, иначе обрывает соединение.
+func (a *App) failBatch(w http.ResponseWriter, r *http.Request, out *batchWriter, err error) {
+	if out.started {
+		a.log(r).Error("batch failed after partial response", zap.Error(err))
+		panic(http.ErrAbortHandler)
+	}
+
+	a.problem(w, r, err)
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %q, got %v", want, tok)
+	}
+	return nil
+}
+
+// expectEOF проверяет, что после значения в теле ничего нет.
+func expectEOF(dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("unexpected %v after the end of the value", tok)
+}
+
+// batchWriter пишет массив ответа по мере сохранения частей батча.
+type batchWriter struct {
+	w       http.ResponseWriter
+	started bool
+}
+
+func (bw *batchWriter) start() {
+	if bw.started {
+		return
+	}
+	bw.started = true
+
+	bw.w.Header().Set("Content-Type", "application/json")
+	bw.w.WriteHeader(http.StatusCreated)
+	bw.w.Write([]byte{'['})
+}
+
+func (bw *batchWriter) write(item serializers.BatchResponseItem) {
+	if bw.started {
+		bw.w.Write([]byte{','})
+	}
+	bw.start()
+
+	jsonBytes, _ := item.MarshalJSON()
+	bw.w.Write(jsonBytes)
+}
+
+func (bw *batchWriter) finish() {
+	bw.start()
+	bw.w.Write([]byte{']'})
+}
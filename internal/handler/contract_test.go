@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Oleg2210/goshortener/api"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type contract struct {
+	doc    *openapi3.T
+	router routers.Router
+	app    http.Handler
+}
+
+func init() {
+	for _, contentType := range []string{"text/html", "text/css", "text/javascript"} {
+		openapi3filter.RegisterBodyDecoder(contentType, func(body io.Reader, _ http.Header, _ *openapi3.SchemaRef, _ openapi3filter.EncodingFn) (any, error) {
+			data, err := io.ReadAll(body)
+			return string(data), err
+		})
+	}
+}
+
+func newContract(t *testing.T) *contract {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(api.OpenAPI)
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(context.Background()))
+
+	// спецификация описывает пути относительно сервера, а тесты ходят на example.com
+	doc.Servers = nil
+	router, err := legacy.NewRouter(doc)
+	require.NoError(t, err)
+
+	mux := chi.NewRouter()
+	newTestApp().Register(mux, RouteOptions{MaxBodySize: 1 << 10, MaxBatchBodySize: 1 << 20, SwaggerUI: true})
+
+	return &contract{doc: doc, router: router, app: mux}
+}
+
+// do выполняет запрос к приложению и проверяет запрос и ответ по спецификации.
+func (c *contract) do(t *testing.T, method, target, contentType, body string) *http.Response {
+	t.Helper()
+	return c.doAccept(t, method, target, "", contentType, body)
+}
+
+func (c *contract) doAccept(t *testing.T, method, target, accept, contentType, body string) *http.Response {
+	t.Helper()
+
+	newRequest := func() *http.Request {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		if contentType != "" {
+			request.Header.Set("Content-Type", contentType)
+		}
+		if accept != "" {
+			request.Header.Set("Accept", accept)
+		}
+		return request
+	}
+	request := newRequest()
+
+	route, pathParams, err := c.router.FindRoute(request)
+	require.NoError(t, err, "%s %s is not described in openapi.json", method, target)
+
+	requestInput := &openapi3filter.RequestValidationInput{
+		Request:    request,
+		PathParams: pathParams,
+		Route:      route,
+	}
+
+	recorder := httptest.NewRecorder()
+	c.app.ServeHTTP(recorder, newRequest())
+	result := recorder.Result()
+
+	respBody, err := io.ReadAll(result.Body)
+	require.NoError(t, err)
+
+	responseInput := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: requestInput,
+		Status:                 result.StatusCode,
+		Header:                 result.Header,
+		Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	responseInput.SetBodyBytes(respBody)
+
+	err = openapi3filter.ValidateResponse(context.Background(), responseInput)
+	assert.NoError(t, err, "%s %s -> %d %s", method, target, result.StatusCode, respBody)
+
+	result.Body = io.NopCloser(bytes.NewReader(respBody))
+	return result
+}
+
+func TestContractMatchesSpec(t *testing.T) {
+	c := newContract(t)
+
+	resp := c.do(t, http.MethodPost, "/", "text/plain", "https://example.com/plain")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	short, _ := io.ReadAll(resp.Body)
+
+	resp = c.do(t, http.MethodPost, "/api/shorten", "application/json", `{"url":"https://example.com/json"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = c.do(t, http.MethodPost, "/api/shorten", "application/json", `{"url":"https://example.com/json"}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = c.do(t, http.MethodPost, "/api/shorten", "application/json", `{"url":"https://example.com/json","dedupe":false,"scope":"user-1"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = c.do(t, http.MethodPost, "/api/shorten", "application/json", `{"url":`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = c.do(t, http.MethodPost, "/api/shorten", "application/json", `{"url":"`+strings.Repeat("a", 2048)+`"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	resp = c.do(t, http.MethodPost, "/api/shorten/batch", "application/json",
+		`[{"correlation_id":"c1","original_url":"https://example.com/1"}]`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = c.do(t, http.MethodPost, "/api/shorten/batch", "application/json",
+		`[{"correlation_id":"c1","original_url":"https://example.com/2"}]`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	id := string(short)[strings.LastIndex(string(short), "/"):]
+	resp = c.do(t, http.MethodGet, id, "", "")
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+
+	resp = c.do(t, http.MethodHead, id, "", "")
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+
+	resp = c.do(t, http.MethodGet, id+"+", "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = c.do(t, http.MethodGet, "/api/urls"+id, "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = c.do(t, http.MethodGet, "/api/v1/urls/missing", "", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = c.do(t, http.MethodGet, id+"/guide", "", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = c.do(t, http.MethodPut, "/api/urls"+id+"/rules", "application/json",
+		`[{"when":{"device":["ios"]},"target":"https://apps.apple.com/app"}]`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = c.do(t, http.MethodGet, "/api/v1/urls"+id+"/rules", "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = c.do(t, http.MethodDelete, "/api/urls"+id+"/rules", "", "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp = c.do(t, http.MethodPut, "/api/urls"+id+"/variants", "application/json",
+		`[{"name":"a","url":"https://example.com/a","weight":2},{"name":"b","url":"https://example.com/b","weight":1}]`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = c.do(t, http.MethodGet, id, "", "")
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+
+	resp = c.do(t, http.MethodGet, "/api/v1/urls"+id+"/variants", "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = c.do(t, http.MethodPut, "/api/urls"+id+"/variants", "application/json", `[{"name":"a","url":"https://example.com/a","weight":0}]`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = c.do(t, http.MethodGet, "/missing", "", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = c.do(t, http.MethodPost, "/api/v1/shorten", "application/json", `{"url":"https://example.com/v1"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = c.doAccept(t, http.MethodPost, "/api/v1/shorten", "text/plain", "application/json", `{"url":"https://example.com/v1/text"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = c.do(t, http.MethodPost, "/api/v1/shorten/batch", "application/json",
+		`[{"correlation_id":"c3","original_url":"https://example.com/3"}]`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = c.do(t, http.MethodPost, "/api/v2/shorten", "application/json", `{"url":"https://example.com/v2"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = c.doAccept(t, http.MethodPost, "/api/v2/shorten", "image/png", "application/json", `{"url":"https://example.com/v2"}`)
+	assert.Equal(t, http.StatusNotAcceptable, resp.StatusCode)
+
+	resp = c.do(t, http.MethodGet, "/api/v2/urls"+id, "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = c.doAccept(t, http.MethodGet, "/api/v2/urls"+id, "text/plain", "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = c.do(t, http.MethodGet, "/api/v2/urls/missing", "", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = c.do(t, http.MethodGet, "/ping", "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = c.do(t, http.MethodGet, "/api/openapi.json", "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = c.do(t, http.MethodGet, "/api/docs", "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = c.do(t, http.MethodGet, "/api/docs/swagger-ui-bundle.js", "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = c.do(t, http.MethodGet, "/api/docs/swagger-ui.css", "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = c.do(t, http.MethodGet, "/api/docs/index.css", "", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	c := newContract(t)
+
+	err := chi.Walk(c.app.(chi.Routes), func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		// в OpenAPI нет шаблонов на несколько сегментов, хвост описан как {suffix}
+		route = strings.Replace(route, "/*", "/{suffix}", 1)
+		item := c.doc.Paths.Find(route)
+		if assert.NotNil(t, item, "route %s is missing in openapi.json", route) {
+			assert.NotNil(t, item.GetOperation(method), "%s %s is missing in openapi.json", method, route)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
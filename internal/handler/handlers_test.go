@@ -1,6 +1,9 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -9,9 +12,14 @@ import (
 
 	"github.com/Oleg2210/goshortener/internal/config"
 	"github.com/Oleg2210/goshortener/internal/repository"
+	"github.com/Oleg2210/goshortener/internal/serializers"
 	"github.com/Oleg2210/goshortener/internal/service"
+	"github.com/Oleg2210/goshortener/pkg/middleware/limit"
+	"github.com/Oleg2210/goshortener/pkg/middleware/logging"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 func TestReplacePOST(t *testing.T) {
@@ -27,14 +35,16 @@ func TestReplacePOST(t *testing.T) {
 	}
 
 	t.Run(test1.name, func(t *testing.T) {
+		cfg := config.Default()
 		repo := repository.NewMemoryRepository()
 		shortenerService := service.NewShortenerService(
 			repo,
-			config.MinLength,
-			config.MaxLength,
+			cfg.MinLength,
+			cfg.MaxLength,
 		)
 		app := App{
 			ShortenerService: shortenerService,
+			BaseURL:          cfg.BaseURL,
 		}
 
 		requestBody := strings.NewReader("https://yandex.kz")
@@ -60,17 +70,19 @@ func TestHandleGet(t *testing.T) {
 		code int
 	}
 
-	test1 := testData{name: "No id", code: http.StatusBadRequest}
+	test1 := testData{name: "No id", code: http.StatusNotFound}
 
 	t.Run(test1.name, func(t *testing.T) {
+		cfg := config.Default()
 		repo := repository.NewMemoryRepository()
 		shortenerService := service.NewShortenerService(
 			repo,
-			config.MinLength,
-			config.MaxLength,
+			cfg.MinLength,
+			cfg.MaxLength,
 		)
 		app := App{
 			ShortenerService: shortenerService,
+			BaseURL:          cfg.BaseURL,
 		}
 
 		request := httptest.NewRequest(http.MethodGet, "/test/", nil)
@@ -84,3 +96,242 @@ func TestHandleGet(t *testing.T) {
 		assert.Equal(t, test1.code, result.StatusCode)
 	})
 }
+
+func newTestApp() *App {
+	cfg := config.Default()
+	return &App{
+		ShortenerService: service.NewShortenerService(
+			repository.NewMemoryRepository(),
+			cfg.MinLength,
+			cfg.MaxLength,
+		),
+		Logger:  zap.NewNop(),
+		BaseURL: cfg.BaseURL,
+	}
+}
+
+func TestHandlePostBatchJSONInChunks(t *testing.T) {
+	app := newTestApp()
+	app.BatchChunkSize = 2
+
+	var body strings.Builder
+	body.WriteString("[")
+	for i := 0; i < 5; i++ {
+		if i > 0 {
+			body.WriteString(",")
+		}
+		fmt.Fprintf(&body, `{"correlation_id":"id%d","original_url":"https://example.com/%d"}`, i, i)
+	}
+	body.WriteString("]")
+
+	request := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(body.String()))
+	recorder := httptest.NewRecorder()
+	app.HandlePostBatchJSON(recorder, request)
+
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	var items serializers.BatchResponseItemSlice
+	require.NoError(t, items.UnmarshalJSON(recorder.Body.Bytes()))
+	require.Len(t, items, 5)
+	assert.Equal(t, "http://localhost:8080/id4", items[4].ShortURL)
+}
+
+func TestHandlePostBatchJSONDedupe(t *testing.T) {
+	app := newTestApp()
+	existing, err := app.ShortenerService.Shorten(t.Context(), "https://example.com/known")
+	require.NoError(t, err)
+
+	body := `[
+		{"correlation_id":"c1","original_url":"https://example.com/known"},
+		{"correlation_id":"c2","original_url":"https://example.com/new"},
+		{"correlation_id":"c3","original_url":"https://example.com/new"},
+		{"correlation_id":"c4","original_url":"https://example.com/known","dedupe":false}
+	]`
+	request := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	app.HandlePostBatchJSON(recorder, request)
+
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	var items serializers.BatchResponseItemSlice
+	require.NoError(t, items.UnmarshalJSON(recorder.Body.Bytes()))
+	require.Len(t, items, 4)
+	assert.Equal(t, "c1", items[0].CorrelationID)
+	assert.Equal(t, "http://localhost:8080/"+existing, items[0].ShortURL)
+	assert.Equal(t, "http://localhost:8080/c2", items[1].ShortURL)
+	assert.Equal(t, "c3", items[2].CorrelationID)
+	assert.Equal(t, "http://localhost:8080/c2", items[2].ShortURL)
+	assert.Equal(t, "http://localhost:8080/c4", items[3].ShortURL)
+
+	for id, exists := range map[string]bool{"c1": false, "c2": true, "c3": false, "c4": true} {
+		_, err := app.ShortenerService.GetRecord(t.Context(), id)
+		assert.Equal(t, exists, err == nil, id)
+	}
+}
+
+func TestHandlePostBatchJSONInvalid(t *testing.T) {
+	app := newTestApp()
+
+	for _, body := range []string{
+		"",
+		"{}",
+		`[{"correlation_id":"a"}]`,
+		`[{"correlation_id":"a","original_url":"u"}`,
+		`[{"correlation_id":"a","original_url":"u"}]garbage`,
+		`[{"correlation_id":"a","original_url":"u"}] []`,
+	} {
+		request := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(body))
+		recorder := httptest.NewRecorder()
+		app.HandlePostBatchJSON(recorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, body)
+	}
+}
+
+func TestHandlePostBatchJSONRetryAfterAbort(t *testing.T) {
+	app := newTestApp()
+	app.BatchChunkSize = 2
+
+	batch := func(broken int) string {
+		items := make([]string, 5)
+		for i := range items {
+			items[i] = fmt.Sprintf(`{"correlation_id":"id%d","original_url":"https://example.com/%d"}`, i, i)
+		}
+		if broken >= 0 {
+			items[broken] = fmt.Sprintf(`{"correlation_id":"id%d"}`, broken)
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	}
+	post := func(body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(body))
+		recorder := httptest.NewRecorder()
+		app.HandlePostBatchJSON(recorder, request)
+		return recorder
+	}
+	stored := func() []string {
+		var ids []string
+		for i := range 5 {
+			if _, err := app.ShortenerService.GetRecord(t.Context(), fmt.Sprintf("id%d", i)); err == nil {
+				ids = append(ids, fmt.Sprintf("id%d", i))
+			}
+		}
+		return ids
+	}
+
+	// первая часть уже сохранена и отправлена, сбой в четвертом элементе обрывает ответ
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() { post(batch(3)) })
+	assert.Equal(t, []string{"id0", "id1"}, stored())
+
+	recorder := post(batch(-1))
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	var items serializers.BatchResponseItemSlice
+	require.NoError(t, items.UnmarshalJSON(recorder.Body.Bytes()))
+	assert.Len(t, items, 5)
+	assert.Equal(t, []string{"id0", "id1", "id2", "id3", "id4"}, stored())
+}
+
+func TestBodyLimit(t *testing.T) {
+	app := newTestApp()
+	handler := limit.BodyLimit(16)(http.HandlerFunc(app.HandlePost))
+
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://example.com/"+strings.Repeat("a", 32)))
+	request.ContentLength = -1
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+}
+
+func TestProblemDetails(t *testing.T) {
+	app := newTestApp()
+
+	request := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader("{"))
+	recorder := httptest.NewRecorder()
+	logging.RequestIDMiddleware(http.HandlerFunc(app.HandlePostJSON)).ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, problemContentType, recorder.Header().Get("Content-Type"))
+
+	var p serializers.Problem
+	require.NoError(t, p.UnmarshalJSON(recorder.Body.Bytes()))
+	assert.Equal(t, "invalid_json", p.Code)
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, "/api/shorten", p.Instance)
+	assert.Equal(t, recorder.Header().Get(logging.RequestIDHeader), p.RequestID)
+
+	request = httptest.NewRequest(http.MethodGet, "/missing", nil)
+	recorder = httptest.NewRecorder()
+	app.HandleGet(recorder, request)
+	require.NoError(t, p.UnmarshalJSON(recorder.Body.Bytes()))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, "not_found", p.Code)
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want apiError
+	}{
+		{service.ErrIDDoesNotExists, problemNotFound},
+		{service.ErrURLExists, problemURLExists},
+		{repository.ErrAlreadyExists, problemIDExists},
+		{service.ErrOutOfCombinations, problemOutOfCombinations},
+		{context.Canceled, problemCanceled},
+		{fmt.Errorf("query: %w", &pgconn.PgError{Code: "57P01"}), problemStorage},
+		{fmt.Errorf("%w: circuit open", repository.ErrUnavailable), problemStorage},
+		{errors.New("boom"), problemInternal},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, classifyError(tt.err), tt.err.Error())
+	}
+}
+
+func TestLegacyPostKeepsPlainText(t *testing.T) {
+	app := newTestApp()
+	handler := limit.BodyLimit(4)(http.HandlerFunc(app.HandlePost))
+
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://example.com"))
+	request.ContentLength = -1
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "text/plain")
+}
+
+func TestShortenDedupe(t *testing.T) {
+	app := newTestApp()
+
+	shorten := func(body string) (int, string) {
+		request := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+		recorder := httptest.NewRecorder()
+		app.HandlePostJSON(recorder, request)
+
+		var resp serializers.Response
+		require.NoError(t, resp.UnmarshalJSON(recorder.Body.Bytes()), recorder.Body.String())
+		return recorder.Code, resp.Result
+	}
+
+	code, first := shorten(`{"url":"https://example.com/dup"}`)
+	assert.Equal(t, http.StatusCreated, code)
+
+	code, again := shorten(`{"url":"https://example.com/dup"}`)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, first, again)
+
+	code, copied := shorten(`{"url":"https://example.com/dup","dedupe":false}`)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.NotEqual(t, first, copied)
+
+	code, scoped := shorten(`{"url":"https://example.com/dup","scope":"user-42"}`)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.NotEqual(t, first, scoped)
+
+	code, again = shorten(`{"url":"https://example.com/dup","scope":"user-42"}`)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, scoped, again)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://example.com/dup","scope":"`+strings.Repeat("x", 200)+`"}`))
+	recorder := httptest.NewRecorder()
+	app.HandlePostJSON(recorder, request)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	_ "embed"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/serializers"
+	"github.com/go-chi/chi/v5"
+)
+
+// суффикс короткой ссылки, по которому вместо редиректа показывается страница предпросмотра
+const previewSuffix = "+"
+
+// ссылки пока не истекают и не удаляются, поэтому найденная ссылка всегда активна
+const linkStatusActive = "active"
+
+//go:embed preview.html
+var previewHTML string
+
+var previewTemplate = template.Must(template.New("preview").Parse(previewHTML))
+
+type previewData struct {
+	ShortURL    string
+	OriginalURL string
+	CreatedAt   time.Time
+}
+
+// HandleGetURLInfo отдает метаданные ссылки, не выполняя редирект.
+func (a *App) HandleGetURLInfo(w http.ResponseWriter, r *http.Request) {
+	record, shortURL, ok := a.lookup(w, r, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+
+	info := serializers.URLInfo{
+		ID:          record.Short,
+		ShortURL:    shortURL,
+		OriginalURL: record.OriginalURL,
+		CreatedAt:   record.CreatedAt,
+		Status:      linkStatusActive,
+	}
+	jsonBytes, _ := info.MarshalJSON()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonBytes)
+}
+
+// HandleHead отвечает так же, как GET /{id}, но без тела, чтобы ссылку
+// можно было проверить, не переходя по ней.
+func (a *App) HandleHead(w http.ResponseWriter, r *http.Request) {
+	id, preview := isPreview(chi.URLParam(r, "id"))
+
+	record, _, ok := a.lookup(w, r, id)
+	if !ok {
+		return
+	}
+
+	if !record.CreatedAt.IsZero() {
+		w.Header().Set("Last-Modified", record.CreatedAt.UTC().Format(http.TimeFormat))
+	}
+	if preview {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	a.writeRedirect(w, r, a.resolve(w, r, record))
+}
+
+// handlePreview показывает адрес назначения для /{id}+ вместо редиректа.
+func (a *App) handlePreview(w http.ResponseWriter, r *http.Request, id string) {
+	record, shortURL, ok := a.lookup(w, r, id)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if err := previewTemplate.Execute(w, previewData{
+		ShortURL:    shortURL,
+		OriginalURL: record.OriginalURL,
+		CreatedAt:   record.CreatedAt,
+	}); err != nil {
+		a.log(r).Warn("preview render failed")
+	}
+}
+
+// lookup находит запись и собирает ее короткую ссылку. При ошибке ответ уже отправлен.
+func (a *App) lookup(w http.ResponseWriter, r *http.Request, id string) (entities.URLRecord, string, bool) {
+	record, err := a.ShortenerService.GetRecord(r.Context(), id)
+	if err != nil {
+		a.problem(w, r, err)
+		return entities.URLRecord{}, "", false
+	}
+
+	shortURL, err := url.JoinPath(a.BaseURL, record.Short)
+	if err != nil {
+		a.problem(w, r, err)
+		return entities.URLRecord{}, "", false
+	}
+
+	return record, shortURL, true
+}
+
+func isPreview(id string) (string, bool) {
+	return strings.CutSuffix(id, previewSuffix)
+}
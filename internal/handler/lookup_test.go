@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Oleg2210/goshortener/internal/serializers"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupDoesNotRedirect(t *testing.T) {
+	app := newTestApp()
+	id, err := app.ShortenerService.Shorten(context.Background(), `https://example.com/?q=<script>`)
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	app.Register(router, RouteOptions{MaxBodySize: 1 << 10, MaxBatchBodySize: 1 << 10})
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+		return recorder
+	}
+
+	info := serve(http.MethodGet, "/api/urls/"+id)
+	require.Equal(t, http.StatusOK, info.Code)
+	var got serializers.URLInfo
+	require.NoError(t, got.UnmarshalJSON(info.Body.Bytes()))
+	assert.Equal(t, id, got.ID)
+	assert.Equal(t, `https://example.com/?q=<script>`, got.OriginalURL)
+	assert.Equal(t, linkStatusActive, got.Status)
+	assert.WithinDuration(t, time.Now(), got.CreatedAt, time.Minute)
+
+	head := serve(http.MethodHead, "/"+id)
+	assert.Equal(t, http.StatusTemporaryRedirect, head.Code)
+	assert.Equal(t, got.OriginalURL, head.Header().Get("Location"))
+	assert.NotEmpty(t, head.Header().Get("Last-Modified"))
+	assert.Zero(t, head.Body.Len())
+
+	preview := serve(http.MethodGet, "/"+id+"+")
+	assert.Equal(t, http.StatusOK, preview.Code)
+	assert.Empty(t, preview.Header().Get("Location"))
+	assert.Contains(t, preview.Body.String(), "https://example.com/?q=&lt;script&gt;")
+	assert.NotContains(t, preview.Body.String(), "<script>")
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/missing+").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodHead, "/missing").Code)
+}
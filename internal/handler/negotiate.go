@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mailru/easyjson"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	mediaJSON        = "application/json"
+	mediaMsgpack     = "application/msgpack"
+	mediaMsgpackLong = "application/x-msgpack"
+	mediaText        = "text/plain"
+)
+
+// форматы ответа в порядке предпочтения сервера
+var representations = []string{mediaJSON, mediaMsgpack, mediaMsgpackLong, mediaText}
+
+var errNotAcceptable = errors.New("none of the accepted media types is supported")
+
+type acceptedMedia struct {
+	mediaType string
+	q         float64
+	// чем конкретнее диапазон, тем выше приоритет при одинаковом q
+	specificity int
+}
+
+func parseAccept(header string) []acceptedMedia {
+	var accepted []acceptedMedia
+
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		specificity := 2
+		switch {
+		case mediaType == "*/*":
+			specificity = 0
+		case strings.HasSuffix(mediaType, "/*"):
+			specificity = 1
+		}
+
+		accepted = append(accepted, acceptedMedia{mediaType: mediaType, q: q, specificity: specificity})
+	}
+
+	return accepted
+}
+
+func (a acceptedMedia) matches(mediaType string) bool {
+	switch a.specificity {
+	case 0:
+		return true
+	case 1:
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(a.mediaType, "*"))
+	default:
+		return a.mediaType == mediaType
+	}
+}
+
+// negotiate выбирает формат ответа по заголовку Accept с учетом q-значений.
+// Пустой Accept означает JSON. Если подходящего формата нет, возвращается errNotAcceptable.
+func negotiate(header string) (string, error) {
+	if strings.TrimSpace(header) == "" {
+		return mediaJSON, nil
+	}
+	accepted := parseAccept(header)
+
+	type candidate struct {
+		mediaType string
+		q         float64
+	}
+	candidates := make([]candidate, 0, len(representations))
+
+	for _, offer := range representations {
+		// q берется из самого конкретного подходящего диапазона
+		best := acceptedMedia{specificity: -1}
+		for _, a := range accepted {
+			if a.matches(offer) && a.specificity > best.specificity {
+				best = a
+			}
+		}
+		if best.specificity >= 0 && best.q > 0 {
+			candidates = append(candidates, candidate{mediaType: offer, q: best.q})
+		}
+	}
+	if len(candidates) == 0 {
+		return "", errNotAcceptable
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	return candidates[0].mediaType, nil
+}
+
+// representation — ответ, который умеет отдаваться в любом из поддерживаемых форматов.
+type representation interface {
+	easyjson.Marshaler
+	// Text — представление для text/plain
+	Text() string
+}
+
+// respond кодирует v в формате mediaType, выбранном negotiate.
+func (a *App) respond(w http.ResponseWriter, r *http.Request, status int, mediaType string, v representation) {
+	var (
+		body []byte
+		err  error
+	)
+
+	contentType := mediaType
+	switch mediaType {
+	case mediaMsgpack, mediaMsgpackLong:
+		body, err = msgpack.Marshal(v)
+	case mediaText:
+		contentType = "text/plain; charset=utf-8"
+		body = []byte(v.Text())
+	default:
+		contentType = mediaJSON
+		body, err = easyjson.Marshal(v)
+	}
+
+	if err != nil {
+		a.problem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+	w.Write(body)
+}
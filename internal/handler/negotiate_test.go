@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Oleg2210/goshortener/internal/serializers"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+		err    error
+	}{
+		{"", mediaJSON, nil},
+		{"*/*", mediaJSON, nil},
+		{"application/msgpack", mediaMsgpack, nil},
+		{"application/x-msgpack", mediaMsgpackLong, nil},
+		{"text/*", mediaText, nil},
+		{"application/json;q=0.5, application/msgpack", mediaMsgpack, nil},
+		{"text/plain, */*;q=0.1", mediaText, nil},
+		{"*/*, application/json;q=0", mediaMsgpack, nil},
+		{"image/png", "", errNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		got, err := negotiate(tt.header)
+		assert.Equal(t, tt.want, got, tt.header)
+		assert.ErrorIs(t, err, tt.err, tt.header)
+	}
+}
+
+func TestV2ShortenFormats(t *testing.T) {
+	router := chi.NewRouter()
+	newTestApp().Register(router, RouteOptions{MaxBodySize: 1 << 10, MaxBatchBodySize: 1 << 10})
+
+	post := func(accept string) *http.Response {
+		request := httptest.NewRequest(http.MethodPost, "/api/v2/shorten", strings.NewReader(`{"url":"https://example.com/`+accept+`"}`))
+		request.Header.Set("Accept", accept)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder.Result()
+	}
+
+	resp := post("application/json")
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	body, _ := io.ReadAll(resp.Body)
+	var link serializers.Link
+	require.NoError(t, link.UnmarshalJSON(body))
+	assert.Equal(t, "https://example.com/application/json", link.OriginalURL)
+	assert.Equal(t, "http://localhost:8080/"+link.ID, link.ShortURL)
+	assert.WithinDuration(t, time.Now(), link.CreatedAt, time.Minute)
+	assert.Nil(t, link.ExpiresAt)
+	assert.Contains(t, string(body), `"expires_at":null`)
+
+	resp = post("application/msgpack")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "application/msgpack", resp.Header.Get("Content-Type"))
+	body, _ = io.ReadAll(resp.Body)
+	var decoded serializers.Link
+	require.NoError(t, msgpack.Unmarshal(body, &decoded))
+	assert.Equal(t, "https://example.com/application/msgpack", decoded.OriginalURL)
+	assert.False(t, decoded.CreatedAt.IsZero())
+
+	lookup := httptest.NewRequest(http.MethodGet, "/api/v2/urls/"+link.ID, nil)
+	lookup.Header.Set("Accept", "text/plain")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, lookup)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, link.ShortURL, recorder.Body.String())
+
+	resp = post("application/xml")
+	assert.Equal(t, http.StatusNotAcceptable, resp.StatusCode)
+	assert.Equal(t, problemContentType, resp.Header.Get("Content-Type"))
+}
+
+func TestV1FallsBackToJSON(t *testing.T) {
+	router := chi.NewRouter()
+	newTestApp().Register(router, RouteOptions{MaxBodySize: 1 << 10, MaxBatchBodySize: 1 << 10})
+
+	for _, path := range []string{"/api/shorten", "/api/v1/shorten"} {
+		request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"url":"https://example.com/`+path+`"}`))
+		request.Header.Set("Accept", "application/xml")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		assert.Equal(t, http.StatusCreated, recorder.Code, path)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"), path)
+
+		var resp serializers.Response
+		assert.NoError(t, resp.UnmarshalJSON(recorder.Body.Bytes()), path)
+	}
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/Oleg2210/goshortener/internal/service"
+)
+
+var errInvalidPath = errors.New("invalid path suffix")
+
+// joinSuffix дописывает к пути original суффикс из запроса.
+func joinSuffix(original string, escapedSuffix string) (string, error) {
+	suffix, err := cleanSuffix(escapedSuffix)
+	if err != nil {
+		return "", err
+	}
+	return appendSuffix(original, suffix), nil
+}
+
+// cleanSuffix проверяет суффикс из запроса. Каждый сегмент декодируется и
+// кодируется заново, поэтому закодированные «/», «\», «.» и «..» не могут
+// вывести адрес за пределы исходного пути.
+func cleanSuffix(escapedSuffix string) (string, error) {
+	if escapedSuffix == "" {
+		return "", nil
+	}
+
+	segments := strings.Split(escapedSuffix, "/")
+	clean := make([]string, 0, len(segments))
+
+	for i, segment := range segments {
+		if segment == "" {
+			// допускается только завершающий слеш
+			if i == len(segments)-1 && i > 0 {
+				clean = append(clean, "")
+				continue
+			}
+			return "", errInvalidPath
+		}
+
+		decoded, err := url.PathUnescape(segment)
+		if err != nil || decoded == "." || decoded == ".." || strings.ContainsAny(decoded, `/\`) {
+			return "", errInvalidPath
+		}
+		if strings.ContainsFunc(decoded, unicode.IsControl) {
+			return "", errInvalidPath
+		}
+
+		clean = append(clean, url.PathEscape(decoded))
+	}
+
+	return strings.Join(clean, "/"), nil
+}
+
+// appendSuffix дописывает к пути original суффикс, уже прошедший cleanSuffix.
+func appendSuffix(original string, suffix string) string {
+	if suffix == "" {
+		return original
+	}
+
+	base, rest := original, ""
+	if i := strings.IndexAny(original, "?#"); i >= 0 {
+		base, rest = original[:i], original[i:]
+	}
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+
+	return base + suffix + rest
+}
+
+// HandleGetPrefix обслуживает /{id}/* для префиксных ссылок.
+func (a *App) HandleGetPrefix(w http.ResponseWriter, r *http.Request) {
+	// суффикс берется из экранированного пути, чтобы %2F не превратился в разделитель
+	rawID, suffix, _ := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+
+	id, err := url.PathUnescape(rawID)
+	if err != nil {
+		a.problem(w, r, service.ErrIDDoesNotExists)
+		return
+	}
+
+	record, err := a.ShortenerService.GetRecord(r.Context(), id)
+	if err != nil {
+		a.problem(w, r, err)
+		return
+	}
+	if !record.Redirect.Prefix {
+		a.problem(w, r, service.ErrIDDoesNotExists)
+		return
+	}
+
+	// суффикс проверяется до resolve: неверный путь не засчитывает клик и не ставит cookie
+	suffix, err = cleanSuffix(suffix)
+	if err != nil {
+		a.problem(w, r, err)
+		return
+	}
+
+	record = a.resolve(w, r, record)
+	record.OriginalURL = appendSuffix(record.OriginalURL, suffix)
+
+	a.writeRedirect(w, r, record)
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinSuffix(t *testing.T) {
+	tests := []struct {
+		original string
+		suffix   string
+		want     string
+		err      bool
+	}{
+		{"https://docs.example.com/v2/", "guide/intro", "https://docs.example.com/v2/guide/intro", false},
+		{"https://docs.example.com/v2", "guide/intro", "https://docs.example.com/v2/guide/intro", false},
+		{"https://docs.example.com", "guide", "https://docs.example.com/guide", false},
+		{"https://docs.example.com/v2/", "", "https://docs.example.com/v2/", false},
+		{"https://docs.example.com/v2/", "guide/", "https://docs.example.com/v2/guide/", false},
+		{"https://docs.example.com/v2/?lang=en#top", "guide", "https://docs.example.com/v2/guide?lang=en#top", false},
+		{"https://docs.example.com/v2/", "a%20b/%D1%8F", "https://docs.example.com/v2/a%20b/%D1%8F", false},
+		{"https://docs.example.com/v2/", "a;b", "https://docs.example.com/v2/a%3Bb", false},
+		{"https://docs.example.com/v2/", "../admin", "", true},
+		{"https://docs.example.com/v2/", "guide/./intro", "", true},
+		{"https://docs.example.com/v2/", "%2e%2e/admin", "", true},
+		{"https://docs.example.com/v2/", "..%2Fadmin", "", true},
+		{"https://docs.example.com/v2/", "a%5C..%5Cadmin", "", true},
+		{"https://docs.example.com/v2/", "a//b", "", true},
+		{"https://docs.example.com/v2/", "/evil.com", "", true},
+		{"https://docs.example.com/v2/", "a%0d%0aSet-Cookie:x", "", true},
+		{"https://docs.example.com/v2/", "%zz", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := joinSuffix(tt.original, tt.suffix)
+		if tt.err {
+			assert.ErrorIs(t, err, errInvalidPath, tt.suffix)
+			continue
+		}
+		assert.NoError(t, err, tt.suffix)
+		assert.Equal(t, tt.want, got, tt.suffix)
+	}
+}
+
+func TestPrefixLinks(t *testing.T) {
+	app := newTestApp()
+	router := chi.NewRouter()
+	app.Register(router, RouteOptions{MaxBodySize: 1 << 10, MaxBatchBodySize: 1 << 10})
+
+	prefix, err := app.ShortenerService.ShortenWithPolicy(t.Context(), "https://docs.example.com/v2/", entities.RedirectPolicy{Prefix: true})
+	require.NoError(t, err)
+	plain, err := app.ShortenerService.Shorten(t.Context(), "https://example.com/plain")
+	require.NoError(t, err)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		return recorder
+	}
+
+	resp := get("/" + prefix + "/guide/intro")
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.Code)
+	assert.Equal(t, "https://docs.example.com/v2/guide/intro", resp.Header().Get("Location"))
+
+	resp = get("/" + prefix)
+	assert.Equal(t, "https://docs.example.com/v2/", resp.Header().Get("Location"))
+
+	assert.Equal(t, http.StatusBadRequest, get("/"+prefix+"/%2e%2e/secret").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/"+prefix+"/a%2F..%2F..%2Fsecret").Code)
+	assert.Equal(t, http.StatusNotFound, get("/"+plain+"/guide").Code)
+	assert.Equal(t, http.StatusNotFound, get("/missing/guide").Code)
+}
+
+func TestPrefixInvalidSuffixSkipsVariants(t *testing.T) {
+	app := newTestApp()
+	router := chi.NewRouter()
+	app.Register(router, RouteOptions{MaxBodySize: 1 << 10, MaxBatchBodySize: 1 << 10})
+
+	id, err := app.ShortenerService.ShortenWithPolicy(t.Context(), "https://docs.example.com/v2/", entities.RedirectPolicy{Prefix: true})
+	require.NoError(t, err)
+	require.NoError(t, app.ShortenerService.SetVariants(t.Context(), id, []entities.Variant{
+		{Name: "a", URL: "https://a.example.com/", Weight: 1},
+		{Name: "b", URL: "https://b.example.com/", Weight: 1},
+	}))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/"+id+"/%2e%2e/secret", nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Empty(t, recorder.Result().Cookies())
+
+	record, err := app.ShortenerService.GetRecord(t.Context(), id)
+	require.NoError(t, err)
+	for _, v := range record.Variants {
+		assert.Zero(t, v.Clicks, v.Name)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/"+id+"/guide", nil))
+	assert.Equal(t, http.StatusTemporaryRedirect, recorder.Code)
+	assert.Regexp(t, `^https://[ab]\.example\.com/guide$`, recorder.Header().Get("Location"))
+	assert.Len(t, recorder.Result().Cookies(), 1)
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/repository"
+	"github.com/Oleg2210/goshortener/internal/rules"
+	"github.com/Oleg2210/goshortener/internal/serializers"
+	"github.com/Oleg2210/goshortener/internal/service"
+	"github.com/Oleg2210/goshortener/pkg/middleware/logging"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+const problemContentType = "application/problem+json"
+
+const problemTypePrefix = "urn:goshortener:problem:"
+
+// нестандартный статус nginx: клиент закрыл соединение, не дождавшись ответа
+const statusClientClosedRequest = 499
+
+var (
+	errInvalidJSON = errors.New("invalid json")
+	errInvalidBody = errors.New("invalid request body")
+
+	errStorageUnavailable = errors.New("storage is unavailable")
+)
+
+// apiError — стабильный код ошибки и HTTP-статус, под которым она отдается клиенту.
+type apiError struct {
+	status int
+	code   string
+}
+
+var (
+	problemInvalidJSON       = apiError{http.StatusBadRequest, "invalid_json"}
+	problemInvalidBody       = apiError{http.StatusBadRequest, "invalid_body"}
+	problemInvalidPath       = apiError{http.StatusBadRequest, "invalid_path"}
+	problemBodyTooLarge      = apiError{http.StatusRequestEntityTooLarge, "body_too_large"}
+	problemNotFound          = apiError{http.StatusNotFound, "not_found"}
+	problemNotAcceptable     = apiError{http.StatusNotAcceptable, "not_acceptable"}
+	problemURLExists         = apiError{http.StatusConflict, "url_exists"}
+	problemIDExists          = apiError{http.StatusConflict, "id_exists"}
+	problemOutOfCombinations = apiError{http.StatusServiceUnavailable, "out_of_combinations"}
+	problemStorage           = apiError{http.StatusServiceUnavailable, "storage_unavailable"}
+	problemCanceled          = apiError{statusClientClosedRequest, "request_canceled"}
+	problemTimeout           = apiError{http.StatusGatewayTimeout, "timeout"}
+	problemInternal          = apiError{http.StatusInternalServerError, "internal"}
+)
+
+func classifyError(err error) apiError {
+	var pgErr *pgconn.PgError
+
+	switch {
+	case isTooLarge(err):
+		return problemBodyTooLarge
+	case errors.Is(err, errInvalidJSON), errors.Is(err, errInvalidBatchItem):
+		return problemInvalidJSON
+	case errors.Is(err, errInvalidBody), errors.Is(err, entities.ErrInvalidRedirectPolicy), errors.Is(err, rules.ErrInvalidRule),
+		errors.Is(err, entities.ErrInvalidVariant), errors.Is(err, entities.ErrInvalidScope):
+		return problemInvalidBody
+	case errors.Is(err, errInvalidPath):
+		return problemInvalidPath
+	case errors.Is(err, service.ErrIDDoesNotExists):
+		return problemNotFound
+	case errors.Is(err, errNotAcceptable):
+		return problemNotAcceptable
+	case errors.Is(err, service.ErrURLExists), errors.Is(err, repository.ErrOriginalExists):
+		return problemURLExists
+	case errors.Is(err, repository.ErrAlreadyExists):
+		return problemIDExists
+	case errors.Is(err, service.ErrOutOfCombinations):
+		return problemOutOfCombinations
+	case errors.Is(err, context.Canceled):
+		return problemCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return problemTimeout
+	case errors.Is(err, errStorageUnavailable), errors.Is(err, repository.ErrUnavailable), errors.As(err, &pgErr), pgconn.SafeToRetry(err), pgconn.Timeout(err):
+		return problemStorage
+	default:
+		return problemInternal
+	}
+}
+
+func (a *App) logError(r *http.Request, e apiError, err error) {
+	if e.status >= http.StatusInternalServerError {
+		a.log(r).Error("request failed", zap.String("code", e.code), zap.Error(err))
+	}
+}
+
+// problem отвечает ошибкой в формате application/problem+json.
+func (a *App) problem(w http.ResponseWriter, r *http.Request, err error) {
+	e := classifyError(err)
+	a.logError(r, e, err)
+
+	p := serializers.Problem{
+		Type:      problemTypePrefix + e.code,
+		Title:     http.StatusText(e.status),
+		Status:    e.status,
+		Instance:  r.URL.Path,
+		Code:      e.code,
+		RequestID: logging.RequestIDFromContext(r.Context()),
+	}
+	if p.Title == "" {
+		p.Title = "Client Closed Request"
+	}
+	// детали внутренних ошибок наружу не отдаются
+	if e.status < http.StatusInternalServerError {
+		p.Detail = err.Error()
+	}
+
+	jsonBytes, _ := p.MarshalJSON()
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(e.status)
+	w.Write(jsonBytes)
+}
+
+// plainError отвечает текстом для legacy-маршрута POST /.
+func (a *App) plainError(w http.ResponseWriter, r *http.Request, err error) {
+	e := classifyError(err)
+	a.logError(r, e, err)
+
+	text := http.StatusText(e.status)
+	if text == "" {
+		text = "Client Closed Request"
+	}
+	http.Error(w, text, e.status)
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+)
+
+type queryParam struct {
+	// декодированное имя, по нему сравниваются параметры
+	name string
+	// сегмент name=value в том виде, в каком он попадет в адрес
+	raw string
+}
+
+func encodeParam(name, value string) string {
+	return url.QueryEscape(name) + "=" + url.QueryEscape(value)
+}
+
+// splitQuery разбивает строку запроса на параметры, сохраняя их порядок и кодировку.
+func splitQuery(raw string) []queryParam {
+	var params []queryParam
+	for _, segment := range strings.Split(raw, "&") {
+		if segment == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(segment, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		params = append(params, queryParam{name: name, raw: segment})
+	}
+	return params
+}
+
+// incomingParams разбирает запрос клиента и заново кодирует каждый параметр,
+// чтобы в Location не попало ничего, кроме корректно экранированных значений.
+// Сегменты с битым экранированием отбрасываются.
+func incomingParams(raw string) []queryParam {
+	var params []queryParam
+	for _, segment := range strings.Split(raw, "&") {
+		if segment == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(segment, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil || name == "" {
+			continue
+		}
+		value, err = url.QueryUnescape(value)
+		if err != nil {
+			continue
+		}
+		params = append(params, queryParam{name: name, raw: encodeParam(name, value)})
+	}
+	return params
+}
+
+// destination собирает адрес редиректа из исходного URL, строки запроса
+// к короткой ссылке и политики ссылки. Путь и фрагмент исходного URL, а также
+// его собственные параметры остаются в исходной кодировке.
+func destination(original string, rawQuery string, policy entities.RedirectPolicy) string {
+	mode := policy.QueryMode
+	if mode == "" {
+		mode = entities.QueryIgnore
+	}
+	if (mode == entities.QueryIgnore || rawQuery == "") && len(policy.DefaultParams) == 0 {
+		return original
+	}
+
+	base, fragment, hasFragment := strings.Cut(original, "#")
+	base, query, _ := strings.Cut(base, "?")
+	params := splitQuery(query)
+
+	var incoming []queryParam
+	if mode != entities.QueryIgnore {
+		incoming = incomingParams(rawQuery)
+	}
+	if mode == entities.QueryAllowlist {
+		incoming = slices.DeleteFunc(incoming, func(p queryParam) bool {
+			return !slices.Contains(policy.QueryAllowlist, p.name)
+		})
+	}
+	if mode == entities.QueryOverride || mode == entities.QueryAllowlist {
+		params = slices.DeleteFunc(params, func(p queryParam) bool {
+			return slices.ContainsFunc(incoming, func(in queryParam) bool { return in.name == p.name })
+		})
+	}
+	params = append(params, incoming...)
+
+	defaults := make([]string, 0, len(policy.DefaultParams))
+	for name := range policy.DefaultParams {
+		defaults = append(defaults, name)
+	}
+	sort.Strings(defaults)
+	for _, name := range defaults {
+		if !slices.ContainsFunc(params, func(p queryParam) bool { return p.name == name }) {
+			params = append(params, queryParam{name: name, raw: encodeParam(name, policy.DefaultParams[name])})
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(base)
+	for i, p := range params {
+		if i == 0 {
+			b.WriteByte('?')
+		} else {
+			b.WriteByte('&')
+		}
+		b.WriteString(p.raw)
+	}
+	if hasFragment {
+		b.WriteByte('#')
+		b.WriteString(fragment)
+	}
+	return b.String()
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDestination(t *testing.T) {
+	utm := map[string]string{"utm_source": "short", "utm_medium": "link"}
+
+	tests := []struct {
+		name     string
+		original string
+		query    string
+		policy   entities.RedirectPolicy
+		want     string
+	}{
+		{
+			name:     "ignore by default",
+			original: "https://example.com/a?x=1",
+			query:    "utm_source=mail",
+			want:     "https://example.com/a?x=1",
+		},
+		{
+			name:     "append keeps duplicates",
+			original: "https://example.com/a?x=1",
+			query:    "x=2&utm_source=mail",
+			policy:   entities.RedirectPolicy{QueryMode: entities.QueryAppend},
+			want:     "https://example.com/a?x=1&x=2&utm_source=mail",
+		},
+		{
+			name:     "override replaces",
+			original: "https://example.com/a?x=1&y=2",
+			query:    "x=3",
+			policy:   entities.RedirectPolicy{QueryMode: entities.QueryOverride},
+			want:     "https://example.com/a?y=2&x=3",
+		},
+		{
+			name:     "allowlist drops unknown",
+			original: "https://example.com/a?utm_source=site",
+			query:    "utm_source=mail&session=42",
+			policy:   entities.RedirectPolicy{QueryMode: entities.QueryAllowlist, QueryAllowlist: []string{"utm_source"}},
+			want:     "https://example.com/a?utm_source=mail",
+		},
+		{
+			name:     "fragment stays last",
+			original: "https://example.com/a?x=1#section?y=2",
+			query:    "utm_source=mail",
+			policy:   entities.RedirectPolicy{QueryMode: entities.QueryAppend},
+			want:     "https://example.com/a?x=1&utm_source=mail#section?y=2",
+		},
+		{
+			name:     "fragment without query",
+			original: "https://example.com/a#top",
+			query:    "q=1",
+			policy:   entities.RedirectPolicy{QueryMode: entities.QueryAppend},
+			want:     "https://example.com/a?q=1#top",
+		},
+		{
+			name:     "incoming values are re-encoded",
+			original: "https://example.com/a",
+			query:    "q=a+b%26c&name=%3Cscript%3E&bad=%zz",
+			policy:   entities.RedirectPolicy{QueryMode: entities.QueryAppend},
+			want:     "https://example.com/a?q=a+b%26c&name=%3Cscript%3E",
+		},
+		{
+			name:     "original encoding is preserved",
+			original: "https://example.com/a%20b?x=%7E&flag",
+			query:    "y=1",
+			policy:   entities.RedirectPolicy{QueryMode: entities.QueryAppend},
+			want:     "https://example.com/a%20b?x=%7E&flag&y=1",
+		},
+		{
+			name:     "defaults fill the gaps",
+			original: "https://example.com/a?utm_medium=site",
+			query:    "",
+			policy:   entities.RedirectPolicy{DefaultParams: utm},
+			want:     "https://example.com/a?utm_medium=site&utm_source=short",
+		},
+		{
+			name:     "incoming beats defaults",
+			original: "https://example.com/a",
+			query:    "utm_source=mail",
+			policy:   entities.RedirectPolicy{QueryMode: entities.QueryOverride, DefaultParams: utm},
+			want:     "https://example.com/a?utm_source=mail&utm_medium=link",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, destination(tt.original, tt.query, tt.policy))
+		})
+	}
+}
+
+func TestRedirectForwardsQuery(t *testing.T) {
+	app := newTestApp()
+	app.Redirect = entities.RedirectPolicy{
+		QueryMode:      entities.QueryAllowlist,
+		QueryAllowlist: []string{"utm_source"},
+	}
+
+	router := chi.NewRouter()
+	app.Register(router, RouteOptions{MaxBodySize: 1 << 10, MaxBatchBodySize: 1 << 10})
+
+	id, err := app.ShortenerService.ShortenWithPolicy(t.Context(), "https://example.com/landing#promo", entities.RedirectPolicy{
+		DefaultParams: map[string]string{"utm_medium": "short link"},
+	})
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/"+id+"?utm_source=mail&token=x", nil))
+
+	assert.Equal(t, http.StatusTemporaryRedirect, recorder.Code)
+	assert.Equal(t, "https://example.com/landing?utm_source=mail&utm_medium=short+link#promo", recorder.Header().Get("Location"))
+}
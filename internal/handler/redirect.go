@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/serializers"
+)
+
+func redirectPolicy(o serializers.RedirectOptions) entities.RedirectPolicy {
+	return entities.RedirectPolicy{
+		Code:           o.RedirectCode,
+		CacheMaxAge:    o.CacheMaxAge,
+		ReferrerPolicy: o.ReferrerPolicy,
+		QueryMode:      entities.QueryMode(o.QueryMode),
+		QueryAllowlist: o.QueryAllowlist,
+		DefaultParams:  o.DefaultParams,
+		Prefix:         o.Prefix,
+	}
+}
+
+// writeRedirect отвечает редиректом на record.OriginalURL с параметрами ссылки,
+// недостающие берутся из глобальных настроек App. Параметры запроса переносятся
+// в адрес назначения по QueryMode ссылки.
+func (a *App) writeRedirect(w http.ResponseWriter, r *http.Request, record entities.URLRecord) {
+	policy := record.Redirect.WithDefaults(a.Redirect)
+	if policy.Code == 0 {
+		policy.Code = http.StatusTemporaryRedirect
+	}
+
+	var maxAge int
+	if policy.CacheMaxAge != nil {
+		maxAge = *policy.CacheMaxAge
+	}
+
+	h := w.Header()
+	switch {
+	case maxAge > 0:
+		// при правилах и вариантах адрес зависит от клиента, кешировать его может только браузер
+		scope := "public"
+		if len(record.Rules) > 0 || len(record.Variants) > 0 {
+			scope = "private"
+		}
+		h.Set("Cache-Control", scope+", max-age="+strconv.Itoa(maxAge))
+		h.Set("Expires", time.Now().Add(time.Duration(maxAge)*time.Second).UTC().Format(http.TimeFormat))
+	case maxAge < 0:
+		h.Set("Cache-Control", "no-store")
+		// некорректная дата в Expires по RFC 9111 означает, что ответ уже устарел
+		h.Set("Expires", "0")
+	}
+	if policy.ReferrerPolicy != "" {
+		h.Set("Referrer-Policy", policy.ReferrerPolicy)
+	}
+
+	h.Set("Location", destination(record.OriginalURL, r.URL.RawQuery, policy))
+	w.WriteHeader(policy.Code)
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedirectPolicy(t *testing.T) {
+	app := newTestApp()
+	app.Redirect = entities.RedirectPolicy{
+		Code:           http.StatusFound,
+		CacheMaxAge:    entities.MaxAge(60),
+		ReferrerPolicy: "no-referrer",
+	}
+
+	router := chi.NewRouter()
+	app.Register(router, RouteOptions{MaxBodySize: 1 << 10, MaxBatchBodySize: 1 << 10})
+
+	get := func(id string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/"+id, nil))
+		return recorder
+	}
+
+	save := func(t *testing.T, url string, policy entities.RedirectPolicy) string {
+		id, err := app.ShortenerService.ShortenWithPolicy(t.Context(), url, policy)
+		require.NoError(t, err)
+		return id
+	}
+
+	defaults := get(save(t, "https://example.com/defaults", entities.RedirectPolicy{}))
+	assert.Equal(t, http.StatusFound, defaults.Code)
+	assert.Equal(t, "public, max-age=60", defaults.Header().Get("Cache-Control"))
+	assert.NotEmpty(t, defaults.Header().Get("Expires"))
+	assert.Equal(t, "no-referrer", defaults.Header().Get("Referrer-Policy"))
+
+	permanent := get(save(t, "https://example.com/permanent", entities.RedirectPolicy{
+		Code:           http.StatusMovedPermanently,
+		CacheMaxAge:    entities.MaxAge(-1),
+		ReferrerPolicy: "origin",
+	}))
+	assert.Equal(t, http.StatusMovedPermanently, permanent.Code)
+	assert.Equal(t, "https://example.com/permanent", permanent.Header().Get("Location"))
+	assert.Equal(t, "no-store", permanent.Header().Get("Cache-Control"))
+	assert.Equal(t, "origin", permanent.Header().Get("Referrer-Policy"))
+
+	uncached := get(save(t, "https://example.com/uncached", entities.RedirectPolicy{CacheMaxAge: entities.MaxAge(0)}))
+	assert.Equal(t, http.StatusFound, uncached.Code)
+	assert.Empty(t, uncached.Header().Get("Cache-Control"), "explicit zero overrides the global lifetime")
+	assert.Empty(t, uncached.Header().Get("Expires"))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/shorten",
+		strings.NewReader(`{"url":"https://example.com/uncached-api","cache_max_age":0}`)))
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	var created struct{ Result string }
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+
+	fromAPI := get(path.Base(created.Result))
+	assert.Equal(t, "https://example.com/uncached-api", fromAPI.Header().Get("Location"))
+	assert.Empty(t, fromAPI.Header().Get("Cache-Control"))
+}
+
+func TestRedirectPolicyAtCreation(t *testing.T) {
+	router := chi.NewRouter()
+	newTestApp().Register(router, RouteOptions{MaxBodySize: 1 << 10, MaxBatchBodySize: 1 << 10})
+
+	post := func(target, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusBadRequest, post("/api/shorten", `{"url":"https://example.com/a","redirect_code":303}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post("/api/shorten", `{"url":"https://example.com/a","referrer_policy":"sometimes"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post("/api/shorten", `{"url":"https://example.com/a","query_mode":"allowlist","query_allowlist":["utm_*"]}`).Code)
+
+	created := post("/api/shorten/batch", `[{"correlation_id":"perm","original_url":"https://example.com/b","redirect_code":308,"cache_max_age":3600}]`)
+	require.Equal(t, http.StatusCreated, created.Code, created.Body.String())
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/perm", nil))
+	assert.Equal(t, http.StatusPermanentRedirect, recorder.Code)
+	assert.Equal(t, "public, max-age=3600", recorder.Header().Get("Cache-Control"))
+	assert.Empty(t, recorder.Header().Get("Referrer-Policy"))
+}
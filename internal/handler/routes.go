@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"github.com/Oleg2210/goshortener/api"
+	"github.com/Oleg2210/goshortener/pkg/middleware/limit"
+	"github.com/go-chi/chi/v5"
+)
+
+type RouteOptions struct {
+	// лимит тела для POST /, /api/shorten и /api/v2/shorten
+	MaxBodySize int64
+	// лимит тела для /api/shorten/batch и /api/v1/shorten/batch
+	MaxBatchBodySize int64
+	// отдавать ли страницу Swagger UI на /api/docs
+	SwaggerUI bool
+}
+
+// Register вешает обработчики App на router. Все маршруты описаны в api/openapi.json.
+func (a *App) Register(router chi.Router, opts RouteOptions) {
+	router.Get("/{id}", a.HandleGet)
+	router.Head("/{id}", a.HandleHead)
+	router.Get("/{id}/*", a.HandleGetPrefix)
+	router.Head("/{id}/*", a.HandleGetPrefix)
+	router.With(limit.BodyLimit(opts.MaxBodySize)).Post("/", a.HandlePost)
+	// /api — алиас /api/v1, оставлен для старых клиентов
+	for _, prefix := range []string{"/api", "/api/v1"} {
+		router.With(limit.BodyLimit(opts.MaxBodySize)).Post(prefix+"/shorten", a.HandlePostJSON)
+		router.With(limit.BodyLimit(opts.MaxBatchBodySize)).Post(prefix+"/shorten/batch", a.HandlePostBatchJSON)
+		router.Get(prefix+"/urls/{id}", a.HandleGetURLInfo)
+		router.Get(prefix+"/urls/{id}/rules", a.HandleGetRules)
+		router.With(limit.BodyLimit(opts.MaxBodySize)).Put(prefix+"/urls/{id}/rules", a.HandlePutRules)
+		router.Delete(prefix+"/urls/{id}/rules", a.HandleDeleteRules)
+		router.Get(prefix+"/urls/{id}/variants", a.HandleGetVariants)
+		router.With(limit.BodyLimit(opts.MaxBodySize)).Put(prefix+"/urls/{id}/variants", a.HandlePutVariants)
+	}
+
+	router.Route("/api/v2", func(r chi.Router) {
+		r.With(limit.BodyLimit(opts.MaxBodySize)).Post("/shorten", a.HandlePostJSONV2)
+		r.Get("/urls/{id}", a.HandleGetLink)
+	})
+	router.Get("/ping", a.HandlePing)
+
+	router.Get("/api/openapi.json", api.SpecHandler)
+	if opts.SwaggerUI {
+		router.Get("/api/docs", api.SwaggerUIHandler)
+		router.Get("/api/docs/{file}", api.SwaggerAssetsHandler.ServeHTTP)
+	}
+}
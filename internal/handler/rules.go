@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/rules"
+	"github.com/Oleg2210/goshortener/internal/serializers"
+	"github.com/go-chi/chi/v5"
+)
+
+func toRules(list serializers.RuleSlice) []entities.Rule {
+	result := make([]entities.Rule, 0, len(list))
+	for _, r := range list {
+		result = append(result, entities.Rule{
+			Conditions: entities.RuleConditions{
+				Devices:   r.When.Device,
+				Languages: r.When.Language,
+				Countries: r.When.Country,
+				From:      r.When.From,
+				Until:     r.When.Until,
+			},
+			Target: r.Target,
+		})
+	}
+	return result
+}
+
+func fromRules(list []entities.Rule) serializers.RuleSlice {
+	result := make(serializers.RuleSlice, 0, len(list))
+	for _, r := range list {
+		result = append(result, serializers.Rule{
+			When: serializers.RuleConditions{
+				Device:   r.Conditions.Devices,
+				Language: r.Conditions.Languages,
+				Country:  r.Conditions.Countries,
+				From:     r.Conditions.From,
+				Until:    r.Conditions.Until,
+			},
+			Target: r.Target,
+		})
+	}
+	return result
+}
+
+func (a *App) writeRules(w http.ResponseWriter, list []entities.Rule) {
+	jsonBytes, _ := fromRules(list).MarshalJSON()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonBytes)
+}
+
+// HandleGetRules отдает правила ссылки в порядке проверки.
+func (a *App) HandleGetRules(w http.ResponseWriter, r *http.Request) {
+	record, err := a.ShortenerService.GetRecord(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		a.problem(w, r, err)
+		return
+	}
+
+	a.writeRules(w, record.Rules)
+}
+
+// HandlePutRules заменяет правила ссылки списком из тела запроса.
+func (a *App) HandlePutRules(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.problem(w, r, bodyError(err))
+		return
+	}
+
+	var req serializers.RuleSlice
+	if err := req.UnmarshalJSON(body); err != nil {
+		a.problem(w, r, jsonError(err))
+		return
+	}
+
+	list := toRules(req)
+	if err := a.ShortenerService.SetRules(r.Context(), chi.URLParam(r, "id"), list); err != nil {
+		a.problem(w, r, err)
+		return
+	}
+
+	a.writeRules(w, list)
+}
+
+// HandleDeleteRules удаляет все правила ссылки.
+func (a *App) HandleDeleteRules(w http.ResponseWriter, r *http.Request) {
+	if err := a.ShortenerService.SetRules(r.Context(), chi.URLParam(r, "id"), nil); err != nil {
+		a.problem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyRules подставляет адрес первого сработавшего правила вместо исходного URL
+// и сообщает, сработало ли правило.
+func (a *App) applyRules(w http.ResponseWriter, r *http.Request, record entities.URLRecord) (entities.URLRecord, bool) {
+	if len(record.Rules) == 0 {
+		return record, false
+	}
+
+	// ответ зависит от клиента, общий кеш не должен отдавать его другим
+	w.Header().Add("Vary", "User-Agent, Accept-Language")
+
+	req := rules.NewRequest(r, record.Rules, a.Geo, time.Now())
+	target, ok := rules.Evaluate(record.Rules, req)
+	if ok {
+		record.OriginalURL = target
+	}
+	return record, ok
+}
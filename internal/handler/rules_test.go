@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticGeo string
+
+func (g staticGeo) Country(net.IP) string {
+	return string(g)
+}
+
+func TestRulesAPIAndRedirect(t *testing.T) {
+	app := newTestApp()
+	app.Geo = staticGeo("DE")
+	app.Redirect = entities.RedirectPolicy{CacheMaxAge: entities.MaxAge(60)}
+
+	router := chi.NewRouter()
+	app.Register(router, RouteOptions{MaxBodySize: 1 << 10, MaxBatchBodySize: 1 << 10})
+
+	id, err := app.ShortenerService.Shorten(t.Context(), "https://example.com/web")
+	require.NoError(t, err)
+
+	serve := func(method, target, body string, header ...string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		for i := 0; i+1 < len(header); i += 2 {
+			request.Header.Set(header[i], header[i+1])
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	resp := serve(http.MethodGet, "/api/urls/"+id+"/rules", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `[]`, resp.Body.String())
+
+	rules := `[
+		{"when":{"device":["ios"]},"target":"https://apps.apple.com/app"},
+		{"when":{"device":["android"]},"target":"https://play.google.com/app"},
+		{"when":{"country":["DE"],"language":["de"]},"target":"https://example.de"}
+	]`
+	resp = serve(http.MethodPut, "/api/v1/urls/"+id+"/rules", rules)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.JSONEq(t, rules, resp.Body.String())
+
+	resp = serve(http.MethodGet, "/"+id, "", "User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)")
+	assert.Equal(t, "https://apps.apple.com/app", resp.Header().Get("Location"))
+	assert.Equal(t, "private, max-age=60", resp.Header().Get("Cache-Control"))
+	assert.Contains(t, resp.Header().Values("Vary"), "User-Agent, Accept-Language")
+
+	resp = serve(http.MethodGet, "/"+id, "", "Accept-Language", "de-DE")
+	assert.Equal(t, "https://example.de", resp.Header().Get("Location"))
+
+	resp = serve(http.MethodGet, "/"+id, "", "Accept-Language", "en")
+	assert.Equal(t, "https://example.com/web", resp.Header().Get("Location"))
+
+	resp = serve(http.MethodPut, "/api/urls/"+id+"/rules", `[{"when":{"device":["tv"]},"target":"https://example.com"}]`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp = serve(http.MethodPut, "/api/urls/missing/rules", `[]`)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	resp = serve(http.MethodDelete, "/api/urls/"+id+"/rules", "")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+
+	resp = serve(http.MethodGet, "/"+id, "", "User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)")
+	assert.Equal(t, "https://example.com/web", resp.Header().Get("Location"))
+	assert.Equal(t, "public, max-age=60", resp.Header().Get("Cache-Control"))
+}
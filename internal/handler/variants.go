@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/serializers"
+	"github.com/Oleg2210/goshortener/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// variantCookie хранит имя варианта, выпавшего посетителю, чтобы при
+// повторных переходах он попадал туда же.
+const (
+	variantCookie    = "gs_variant"
+	variantCookieTTL = 30 * 24 * time.Hour
+)
+
+func toVariants(list serializers.VariantSlice) []entities.Variant {
+	if len(list) == 0 {
+		return nil
+	}
+
+	result := make([]entities.Variant, 0, len(list))
+	for _, v := range list {
+		result = append(result, entities.Variant{
+			Name:   v.Name,
+			URL:    v.URL,
+			Weight: v.Weight,
+		})
+	}
+	return result
+}
+
+func fromVariants(list []entities.Variant) serializers.VariantSlice {
+	result := make(serializers.VariantSlice, 0, len(list))
+	for _, v := range list {
+		result = append(result, serializers.Variant{
+			Name:   v.Name,
+			URL:    v.URL,
+			Weight: v.Weight,
+			Clicks: v.Clicks,
+		})
+	}
+	return result
+}
+
+func (a *App) writeVariants(w http.ResponseWriter, list []entities.Variant) {
+	jsonBytes, _ := fromVariants(list).MarshalJSON()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonBytes)
+}
+
+// HandleGetVariants отдает варианты A/B-ссылки со счетчиками переходов.
+func (a *App) HandleGetVariants(w http.ResponseWriter, r *http.Request) {
+	record, err := a.ShortenerService.GetRecord(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		a.problem(w, r, err)
+		return
+	}
+
+	a.writeVariants(w, record.Variants)
+}
+
+// HandlePutVariants заменяет варианты ссылки. Счетчики вариантов, оставшихся
+// под тем же именем, сохраняются; пустой список выключает A/B-тест.
+func (a *App) HandlePutVariants(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.problem(w, r, bodyError(err))
+		return
+	}
+
+	var req serializers.VariantSlice
+	if err := req.UnmarshalJSON(body); err != nil {
+		a.problem(w, r, jsonError(err))
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if err := a.ShortenerService.SetVariants(r.Context(), id, toVariants(req)); err != nil {
+		a.problem(w, r, err)
+		return
+	}
+
+	record, err := a.ShortenerService.GetRecord(r.Context(), id)
+	if err != nil {
+		a.problem(w, r, err)
+		return
+	}
+	a.writeVariants(w, record.Variants)
+}
+
+// resolve выбирает адрес назначения: сначала правила, затем вариант A/B-теста.
+// Переход засчитывается варианту только для GET, HEAD не меняет счетчики.
+func (a *App) resolve(w http.ResponseWriter, r *http.Request, record entities.URLRecord) entities.URLRecord {
+	record, matched := a.applyRules(w, r, record)
+	if matched || len(record.Variants) == 0 {
+		return record
+	}
+
+	w.Header().Add("Vary", "Cookie")
+
+	sticky := ""
+	if c, err := r.Cookie(variantCookie); err == nil {
+		sticky = c.Value
+	}
+
+	variant, _ := service.ChooseVariant(record.Variants, sticky)
+	record.OriginalURL = variant.URL
+
+	if variant.Name != sticky {
+		http.SetCookie(w, &http.Cookie{
+			Name:     variantCookie,
+			Value:    variant.Name,
+			Path:     "/" + url.PathEscape(record.Short),
+			MaxAge:   int(variantCookieTTL.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	if r.Method != http.MethodHead {
+		a.ShortenerService.CountClick(r.Context(), record.Short, variant.Name)
+	}
+
+	return record
+}
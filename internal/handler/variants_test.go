@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/serializers"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariantsSplitAndStickiness(t *testing.T) {
+	app := newTestApp()
+	router := chi.NewRouter()
+	app.Register(router, RouteOptions{MaxBodySize: 1 << 10, MaxBatchBodySize: 1 << 10})
+
+	serve := func(method, target, body string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		for _, c := range cookies {
+			request.AddCookie(c)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	resp := serve(http.MethodPost, "/api/shorten", `{
+		"url": "https://example.com/landing",
+		"variants": [
+			{"name": "a", "url": "https://example.com/a", "weight": 3},
+			{"name": "b", "url": "https://example.com/b", "weight": 1}
+		]
+	}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	var created serializers.Response
+	require.NoError(t, created.UnmarshalJSON(resp.Body.Bytes()))
+	id := created.Result[strings.LastIndex(created.Result, "/")+1:]
+
+	hits := map[string]int{}
+	for range 400 {
+		resp = serve(http.MethodGet, "/"+id, "")
+		require.Equal(t, http.StatusTemporaryRedirect, resp.Code)
+		hits[resp.Header().Get("Location")]++
+	}
+	assert.InDelta(t, 300, hits["https://example.com/a"], 60)
+	assert.InDelta(t, 100, hits["https://example.com/b"], 60)
+	assert.Contains(t, resp.Header().Values("Vary"), "Cookie")
+
+	cookies := resp.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, variantCookie, cookies[0].Name)
+	assert.Equal(t, "/"+id, cookies[0].Path)
+	assert.True(t, cookies[0].HttpOnly)
+
+	// посетитель с cookie остается на своем варианте, даже с малым весом
+	sticky := &http.Cookie{Name: variantCookie, Value: "b"}
+	for range 20 {
+		resp = serve(http.MethodGet, "/"+id, "", sticky)
+		assert.Equal(t, "https://example.com/b", resp.Header().Get("Location"))
+		assert.Empty(t, resp.Result().Cookies())
+	}
+
+	// HEAD не засчитывается
+	resp = serve(http.MethodHead, "/"+id, "", sticky)
+	assert.Equal(t, "https://example.com/b", resp.Header().Get("Location"))
+
+	resp = serve(http.MethodGet, "/api/urls/"+id+"/variants", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	var variants serializers.VariantSlice
+	require.NoError(t, variants.UnmarshalJSON(resp.Body.Bytes()))
+	require.Len(t, variants, 2)
+	assert.Equal(t, int64(hits["https://example.com/a"]), variants[0].Clicks)
+	assert.Equal(t, int64(hits["https://example.com/b"]+20), variants[1].Clicks)
+
+	// вариант с прежним именем сохраняет счетчик, исчезнувший cookie перевыбирается
+	resp = serve(http.MethodPut, "/api/v1/urls/"+id+"/variants", `[
+		{"name": "a", "url": "https://example.com/a2", "weight": 1},
+		{"name": "c", "url": "https://example.com/c", "weight": 1, "clicks": 1000}
+	]`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NoError(t, variants.UnmarshalJSON(resp.Body.Bytes()))
+	assert.Equal(t, int64(hits["https://example.com/a"]), variants[0].Clicks)
+	assert.Equal(t, int64(0), variants[1].Clicks)
+
+	resp = serve(http.MethodGet, "/"+id, "", sticky)
+	assert.Contains(t, []string{"https://example.com/a2", "https://example.com/c"}, resp.Header().Get("Location"))
+	assert.Len(t, resp.Result().Cookies(), 1)
+
+	resp = serve(http.MethodPut, "/api/urls/"+id+"/variants", `[]`)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `[]`, resp.Body.String())
+
+	resp = serve(http.MethodGet, "/"+id, "", sticky)
+	assert.Equal(t, "https://example.com/landing", resp.Header().Get("Location"))
+	assert.Empty(t, resp.Result().Cookies())
+}
+
+func TestVariantsRulesTakePrecedence(t *testing.T) {
+	app := newTestApp()
+	router := chi.NewRouter()
+	app.Register(router, RouteOptions{MaxBodySize: 1 << 10, MaxBatchBodySize: 1 << 10})
+
+	id, err := app.ShortenerService.ShortenRecord(t.Context(), entities.URLRecord{
+		OriginalURL: "https://example.com/web",
+		Variants:    []entities.Variant{{Name: "only", URL: "https://example.com/only", Weight: 1}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, app.ShortenerService.SetRules(t.Context(), id, []entities.Rule{{
+		Conditions: entities.RuleConditions{Devices: []string{"ios"}},
+		Target:     "https://apps.apple.com/app",
+	}}))
+
+	request := httptest.NewRequest(http.MethodGet, "/"+id, nil)
+	request.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	assert.Equal(t, "https://apps.apple.com/app", recorder.Header().Get("Location"))
+	assert.Empty(t, recorder.Result().Cookies())
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/"+id, nil))
+	assert.Equal(t, "https://example.com/only", recorder.Header().Get("Location"))
+}
+
+func TestVariantsValidation(t *testing.T) {
+	app := newTestApp()
+	router := chi.NewRouter()
+	app.Register(router, RouteOptions{MaxBodySize: 1 << 10, MaxBatchBodySize: 1 << 10})
+
+	id, err := app.ShortenerService.Shorten(t.Context(), "https://example.com/plain")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		status int
+	}{
+		{"duplicate names", "/api/urls/" + id + "/variants", `[{"name":"a","url":"https://a.example","weight":1},{"name":"a","url":"https://b.example","weight":1}]`, http.StatusBadRequest},
+		{"zero weight", "/api/urls/" + id + "/variants", `[{"name":"a","url":"https://a.example","weight":0}]`, http.StatusBadRequest},
+		{"relative url", "/api/urls/" + id + "/variants", `[{"name":"a","url":"/a","weight":1}]`, http.StatusBadRequest},
+		{"bad name", "/api/urls/" + id + "/variants", `[{"name":"a;b","url":"https://a.example","weight":1}]`, http.StatusBadRequest},
+		{"missing link", "/api/urls/missing/variants", `[]`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, tt.target, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.status, recorder.Code, recorder.Body.String())
+		})
+	}
+}
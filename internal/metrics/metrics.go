@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "shortener"
+
+// Registry хранит все метрики приложения и отдается на /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route pattern and status.",
+		},
+		[]string{"method", "route", "status"},
+	)
+
+	HTTPDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"method", "route"},
+	)
+
+	ShortenAttempts = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "shorten_attempts",
+			Help:      "Number of id generation attempts per Shorten call.",
+			Buckets:   prometheus.LinearBuckets(1, 1, 10),
+		},
+	)
+
+	ShortenRetries = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "shorten_retries_total",
+			Help:      "Id collisions that forced Shorten to retry with a longer id.",
+		},
+	)
+
+	OutOfCombinations = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "shorten_out_of_combinations_total",
+			Help:      "Shorten calls that failed with ErrOutOfCombinations.",
+		},
+	)
+
+	StorageDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_duration_seconds",
+			Help:      "URLRepository operation latency by backend and operation.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		},
+		[]string{"backend", "operation"},
+	)
+
+	FileWriteDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "file_storage_write_duration_seconds",
+			Help:      "Time spent rewriting the file storage.",
+			Buckets:   prometheus.DefBuckets,
+		},
+	)
+
+	DBRetries = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_retries_total",
+			Help:      "Database queries repeated after a transient error.",
+		},
+	)
+
+	DBCircuitOpen = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "db_circuit_open",
+			Help:      "1 while the database circuit breaker rejects queries.",
+		},
+	)
+
+	DBCircuitRejections = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_circuit_rejections_total",
+			Help:      "Database queries rejected without a round trip by the open circuit breaker.",
+		},
+	)
+
+	DBReplicaHealthy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "db_replica_healthy",
+			Help:      "1 while the read replica passes health checks.",
+		},
+		[]string{"replica"},
+	)
+
+	DBReads = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_reads_total",
+			Help:      "Database reads by target: replica or primary.",
+		},
+		[]string{"target"},
+	)
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		ShortenAttempts,
+		ShortenRetries,
+		OutOfCombinations,
+		StorageDuration,
+		FileWriteDuration,
+		DBRetries,
+		DBCircuitOpen,
+		DBCircuitRejections,
+		DBReplicaHealthy,
+		DBReads,
+	)
+}
+
+// RegisterDBStats публикует статистику пула соединений db.
+func RegisterDBStats(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterPgxPoolStats публикует статистику нативного пула pgx.
+func RegisterPgxPoolStats(pool *pgxpool.Pool, name string) error {
+	return Registry.Register(newPgxPoolCollector(pool, name))
+}
+
+// Handler отдает метрики в текстовом формате Prometheus.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPgxPoolCollector(t *testing.T) {
+	// пул не подключается, пока у него не попросят соединение
+	pool, err := pgxpool.New(t.Context(), "postgres://user@127.0.0.1:1/db?pool_max_conns=7")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	expected := `
+# HELP go_pgxpool_max_conns Maximum size of the pool.
+# TYPE go_pgxpool_max_conns gauge
+go_pgxpool_max_conns{db_name="postgres"} 7
+# HELP go_pgxpool_total_conns Connections in the pool: acquired, idle and being constructed.
+# TYPE go_pgxpool_total_conns gauge
+go_pgxpool_total_conns{db_name="postgres"} 0
+`
+	err = testutil.CollectAndCompare(newPgxPoolCollector(pool, "postgres"), strings.NewReader(expected),
+		"go_pgxpool_max_conns", "go_pgxpool_total_conns")
+	assert.NoError(t, err)
+}
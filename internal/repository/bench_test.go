@@ -0,0 +1,99 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+// postgresBackends открывает оба Postgres-хранилища на postgresDSN для
+// сравнения database/sql и нативного pgxpool (подтесты driver=..., см. README):
+//
+//	EMBEDDED_POSTGRES=1 go test -run '^$' -bench . -benchmem -count 10 ./internal/repository/ > bench.txt
+//	benchstat -col /driver bench.txt
+func postgresBackends(b *testing.B) map[string]repository.URLRepository {
+	if postgresDSN == "" {
+		b.Skip("TEST_DATABASE_DSN or EMBEDDED_POSTGRES is not set")
+	}
+
+	stdlib, err := repository.NewDBRepository(b.Context(), postgresDSN, repository.DefaultDBOptions())
+	require.NoError(b, err)
+	b.Cleanup(func() { stdlib.Close() })
+
+	pgxpool, err := repository.NewPgxRepository(b.Context(), postgresDSN, repository.DefaultDBOptions())
+	require.NoError(b, err)
+	b.Cleanup(func() { pgxpool.Close() })
+
+	_, err = stdlib.DB.ExecContext(context.Background(), "TRUNCATE urls CASCADE")
+	require.NoError(b, err)
+
+	return map[string]repository.URLRepository{"stdlib": stdlib, "pgxpool": pgxpool}
+}
+
+// BenchmarkRedirect — основной сценарий сервиса: чтение записи по id.
+func BenchmarkRedirect(b *testing.B) {
+	for name, repo := range postgresBackends(b) {
+		short := "bench-" + name
+		_, err := repo.Save(b.Context(), entities.URLRecord{
+			Short:       short,
+			OriginalURL: "https://example.com/" + name,
+			Variants:    []entities.Variant{{Name: "a", URL: "https://example.com/a", Weight: 1}},
+		})
+		require.NoError(b, err)
+
+		b.Run("driver="+name, func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := repo.GetRecord(context.Background(), short); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkSave(b *testing.B) {
+	for name, repo := range postgresBackends(b) {
+		b.Run("driver="+name, func(b *testing.B) {
+			i := 0
+			for b.Loop() {
+				i++
+				_, err := repo.Save(context.Background(), entities.URLRecord{
+					Short:       fmt.Sprintf("save-%s-%d", name, i),
+					OriginalURL: fmt.Sprintf("https://example.com/%s/%d", name, i),
+					Dedupe:      true,
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkBatchSave(b *testing.B) {
+	for name, repo := range postgresBackends(b) {
+		b.Run("driver="+name, func(b *testing.B) {
+			i := 0
+			for b.Loop() {
+				records := make([]entities.URLRecord, 100)
+				for j := range records {
+					i++
+					records[j] = entities.URLRecord{
+						Short:       fmt.Sprintf("batch-%s-%d", name, i),
+						OriginalURL: fmt.Sprintf("https://example.com/batch/%s/%d", name, i),
+						Dedupe:      true,
+					}
+				}
+				if _, err := repo.BatchSave(context.Background(), records); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Oleg2210/goshortener/internal/metrics"
+)
+
+// breaker — выключатель цепи: после threshold отказов подряд хранилище считается
+// недоступным на cooldown, запросы отклоняются сразу, не дожидаясь таймаутов.
+// По истечении cooldown пропускается один пробный запрос: успех замыкает цепь,
+// отказ снова размыкает ее. threshold <= 0 выключает выключатель.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+	// растет при каждом размыкании и замыкании цепи
+	generation uint64
+}
+
+// ticket — разрешение на запрос, выданное allow. Результат запроса, начатого
+// до смены состояния цепи, о текущем состоянии базы не говорит и не учитывается.
+type ticket struct {
+	generation uint64
+	probe      bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// allow сообщает, можно ли выполнить запрос. Разрешение на пробный запрос
+// выдается одному вызывающему, он обязан сообщить результат через record или release.
+func (b *breaker) allow() (ticket, bool) {
+	if b.threshold <= 0 {
+		return ticket{}, true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return ticket{generation: b.generation}, true
+	}
+	if b.probing || b.now().Sub(b.openedAt) < b.cooldown {
+		return ticket{}, false
+	}
+
+	b.probing = true
+	return ticket{generation: b.generation, probe: true}, true
+}
+
+func (b *breaker) record(t ticket, failed bool) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if t.generation != b.generation {
+		return
+	}
+	if t.probe {
+		b.probing = false
+	}
+
+	if !failed {
+		if b.failures >= b.threshold {
+			b.generation++
+		}
+		b.failures = 0
+		metrics.DBCircuitOpen.Set(0)
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.generation++
+		metrics.DBCircuitOpen.Set(1)
+	}
+}
+
+// release снимает запрос, результат которого неизвестен.
+func (b *breaker) release(t ticket) {
+	if b.threshold <= 0 || !t.probe {
+		return
+	}
+
+	b.mu.Lock()
+	if t.generation == b.generation {
+		b.probing = false
+	}
+	b.mu.Unlock()
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// allowed запрашивает разрешение и проверяет, что оно выдано.
+func allowed(t *testing.T, b *breaker, msg string) ticket {
+	t.Helper()
+	tk, ok := b.allow()
+	assert.True(t, ok, msg)
+	return tk
+}
+
+func rejected(b *breaker) bool {
+	_, ok := b.allow()
+	return !ok
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.record(allowed(t, b, "closed"), true)
+	b.record(allowed(t, b, "one failure keeps the circuit closed"), true)
+	assert.True(t, rejected(b), "threshold reached")
+
+	now = now.Add(time.Minute)
+	probe := allowed(t, b, "probe after cooldown")
+	assert.True(t, rejected(b), "only one probe at a time")
+	b.record(probe, true)
+	assert.True(t, rejected(b), "failed probe reopens the circuit")
+
+	now = now.Add(time.Minute)
+	b.release(allowed(t, b, "probe after cooldown"))
+	b.record(allowed(t, b, "released probe can be retried"), false)
+	allowed(t, b, "successful probe closes the circuit")
+	allowed(t, b, "successful probe closes the circuit")
+}
+
+func TestBreakerIgnoresStaleResults(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	// медленные запросы начались, пока цепь была замкнута
+	slowSuccess := allowed(t, b, "closed")
+	slowFailure := allowed(t, b, "closed")
+	slowCancelled := allowed(t, b, "closed")
+
+	b.record(allowed(t, b, "closed"), true)
+	b.record(allowed(t, b, "closed"), true)
+	assert.True(t, rejected(b), "threshold reached")
+
+	b.record(slowSuccess, false)
+	assert.True(t, rejected(b), "result from before the circuit opened must not close it")
+
+	now = now.Add(time.Minute)
+	probe := allowed(t, b, "probe after cooldown")
+
+	b.record(slowSuccess, false)
+	b.record(slowFailure, true)
+	b.release(slowCancelled)
+	assert.True(t, rejected(b), "stale results must not clear the probe")
+
+	b.record(probe, false)
+	allowed(t, b, "successful probe closes the circuit")
+
+	b.record(slowFailure, true)
+	b.record(slowFailure, true)
+	allowed(t, b, "failures from before the circuit closed must not open it")
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := newBreaker(0, time.Minute)
+	for range 10 {
+		tk, _ := b.allow()
+		b.record(tk, true)
+	}
+	_, ok := b.allow()
+	assert.True(t, ok)
+}
@@ -0,0 +1,211 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/repository"
+	"github.com/Oleg2210/goshortener/internal/repository/repositorytest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// backends — фабрики всех хранилищ. Postgres проверяется, если задан
+// TEST_DATABASE_DSN или EMBEDDED_POSTGRES (см. TestMain); таблицы в этой базе
+// очищаются перед каждым подтестом.
+func backends() map[string]repositorytest.Factory {
+	result := map[string]repositorytest.Factory{
+		"memory": func(t *testing.T) repository.URLRepository {
+			return repository.NewMemoryRepository()
+		},
+		"file": func(t *testing.T) repository.URLRepository {
+			repo, err := repository.NewFileRepository(t.Context(), filepath.Join(t.TempDir(), "storage.json"))
+			require.NoError(t, err)
+			t.Cleanup(func() { repo.Close() })
+			return repo
+		},
+		"instrumented": func(t *testing.T) repository.URLRepository {
+			return repository.NewInstrumentedRepository(repository.NewMemoryRepository(), "memory")
+		},
+		"sqlite": func(t *testing.T) repository.URLRepository {
+			return newSQLiteRepository(t)
+		},
+	}
+
+	if dsn := postgresDSN; dsn != "" {
+		result["db"] = func(t *testing.T) repository.URLRepository {
+			repo, err := repository.NewDBRepository(t.Context(), dsn, repository.DefaultDBOptions())
+			require.NoError(t, err)
+			t.Cleanup(func() { repo.Close() })
+
+			_, err = repo.DB.ExecContext(context.Background(), "TRUNCATE urls CASCADE")
+			require.NoError(t, err)
+			return repo
+		}
+		result["replicas"] = func(t *testing.T) repository.URLRepository {
+			// primary служит себе репликой, без окна read-your-writes все чтения идут через нее
+			opts := repository.DefaultDBOptions()
+			opts.Replicas = []string{dsn}
+			opts.ReadYourWritesWindow = 0
+
+			repo, err := repository.NewDBRepository(t.Context(), dsn, opts)
+			require.NoError(t, err)
+			t.Cleanup(func() { repo.Close() })
+
+			_, err = repo.DB.ExecContext(context.Background(), "TRUNCATE urls CASCADE")
+			require.NoError(t, err)
+			return repo
+		}
+		result["pgx"] = func(t *testing.T) repository.URLRepository {
+			repo, err := repository.NewPgxRepository(t.Context(), dsn, repository.DefaultDBOptions())
+			require.NoError(t, err)
+			t.Cleanup(func() { repo.Close() })
+
+			_, err = repo.Pool.Exec(context.Background(), "TRUNCATE urls CASCADE")
+			require.NoError(t, err)
+			return repo
+		}
+		result["sharded"] = func(t *testing.T) repository.URLRepository {
+			return newShardedRepository(t, 2, 0)
+		}
+	}
+
+	return result
+}
+
+func newSQLiteRepository(t *testing.T) *repository.SQLiteRepository {
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "urls.db")
+	repo, err := repository.NewSQLiteRepository(t.Context(), dsn, repository.DefaultDBOptions())
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestConformance(t *testing.T) {
+	for name, factory := range backends() {
+		t.Run(name, func(t *testing.T) {
+			repositorytest.Run(t, factory)
+		})
+	}
+}
+
+func TestFileDedupeIndexAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+
+	// файл старого формата: без scope и no_dedupe, один адрес под двумя id
+	legacy := `[
+		{"uuid":"late","short_url":"late","original_url":"https://example.com/a","created_at":"2024-02-01T00:00:00Z"},
+		{"uuid":"early","short_url":"early","original_url":"https://example.com/a","created_at":"2024-01-01T00:00:00Z"}
+	]`
+	require.NoError(t, os.WriteFile(path, []byte(legacy), 0644))
+
+	repo, err := repository.NewFileRepository(t.Context(), path)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	short, err := repo.Save(t.Context(), entities.URLRecord{Short: "new", OriginalURL: "https://example.com/a", Dedupe: true})
+	require.NoError(t, err)
+	assert.Equal(t, "early", short)
+
+	_, err = repo.Save(t.Context(), entities.URLRecord{Short: "copy", OriginalURL: "https://example.com/b"})
+	require.NoError(t, err)
+	_, err = repo.Save(t.Context(), entities.URLRecord{Short: "scoped", OriginalURL: "https://example.com/b", Scope: "alice", Dedupe: true})
+	require.NoError(t, err)
+
+	repo, err = repository.NewFileRepository(t.Context(), path)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	short, err = repo.Save(t.Context(), entities.URLRecord{Short: "b1", OriginalURL: "https://example.com/b", Dedupe: true})
+	require.NoError(t, err)
+	assert.Equal(t, "b1", short, "record saved without dedupe must stay out of the index")
+
+	short, err = repo.Save(t.Context(), entities.URLRecord{Short: "b2", OriginalURL: "https://example.com/b", Scope: "alice", Dedupe: true})
+	require.NoError(t, err)
+	assert.Equal(t, "scoped", short)
+}
+
+func TestPgxPoolOptions(t *testing.T) {
+	if postgresDSN == "" {
+		t.Skip("TEST_DATABASE_DSN or EMBEDDED_POSTGRES is not set")
+	}
+
+	for name, tt := range map[string]struct{ open, idle, want int32 }{
+		"idle kept open":       {open: 5, idle: 2, want: 2},
+		"capped by open limit": {open: 3, idle: 10, want: 3},
+		"no idle":              {open: 5, idle: 0, want: 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			opts := repository.DefaultDBOptions()
+			opts.MaxOpenConns = int(tt.open)
+			opts.MaxIdleConns = int(tt.idle)
+
+			repo, err := repository.NewPgxRepository(t.Context(), postgresDSN, opts)
+			require.NoError(t, err)
+			defer repo.Close()
+
+			assert.Equal(t, tt.open, repo.Pool.Config().MaxConns)
+			assert.Equal(t, tt.want, repo.Pool.Config().MinConns)
+		})
+	}
+}
+
+func TestDedupeRollbackKeepsDuplicates(t *testing.T) {
+	dsns := map[string]string{"sqlite": "sqlite://" + filepath.Join(t.TempDir(), "urls.db")}
+	if postgresDSN != "" {
+		dsns["postgres"] = postgresDSN
+	}
+
+	for name, dsn := range dsns {
+		t.Run(name, func(t *testing.T) {
+			migrator, err := repository.NewMigrator(dsn, 0)
+			require.NoError(t, err)
+			defer migrator.Close()
+			require.NoError(t, migrator.Up())
+			latest, _, err := migrator.Version()
+			require.NoError(t, err)
+			t.Cleanup(func() { migrator.Up() })
+
+			repo := newDBBackend(t, name, dsn)
+			for _, short := range []string{"dup1", "dup2"} {
+				_, err := repo.Save(t.Context(), entities.URLRecord{Short: short, OriginalURL: "https://example.com/dup"})
+				require.NoError(t, err)
+			}
+
+			// 000010 и 000009 откатываются, 000008 отказывается удалять дубли
+			assert.Error(t, migrator.Down(int(latest)-7))
+
+			version, dirty, err := migrator.Version()
+			require.NoError(t, err)
+			assert.Equal(t, uint(8), version)
+			assert.False(t, dirty, "failed rollback must not leave the schema dirty")
+
+			require.NoError(t, migrator.Up())
+			for _, short := range []string{"dup1", "dup2"} {
+				_, err := repo.GetRecord(t.Context(), short)
+				assert.NoError(t, err, "rollback must not delete links")
+			}
+		})
+	}
+}
+
+// newDBBackend открывает SQL-хранилище по dsn, базу Postgres очищает.
+func newDBBackend(t *testing.T, name, dsn string) repository.URLRepository {
+	if name == "sqlite" {
+		repo, err := repository.NewSQLiteRepository(t.Context(), dsn, repository.DefaultDBOptions())
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	}
+
+	repo, err := repository.NewDBRepository(t.Context(), dsn, repository.DefaultDBOptions())
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+
+	_, err = repo.DB.ExecContext(context.Background(), "TRUNCATE urls CASCADE")
+	require.NoError(t, err)
+	return repo
+}
@@ -3,82 +3,291 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
 
 	"github.com/Oleg2210/goshortener/internal/entities"
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/Oleg2210/goshortener/internal/metrics"
+	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
-func applyMigrations(dsn string) error {
-	m, err := migrate.New(
-		"file://migrations",
-		dsn,
-	)
-	if err != nil {
+// код ошибки Postgres unique_violation
+const uniqueViolation = "23505"
+
+// частичный индекс уникальности по (scope, original) для записей с dedupe, миграция 000008,
+// и первичный ключ глобального индекса шардированного хранилища, миграция 000009
+const (
+	originalIndex = "idx_urls_original"
+	originalsKey  = "url_originals_pkey"
+)
+
+// translateError приводит нарушение уникальности к ошибкам пакета repository.
+func translateError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
 		return err
 	}
 
-	err = m.Up()
-	if err != nil && err != migrate.ErrNoChange {
-		return err
+	if pgErr.ConstraintName == originalIndex || pgErr.ConstraintName == originalsKey {
+		return ErrOriginalExists
 	}
+	return ErrAlreadyExists
+}
 
-	return nil
+// recordArgs возвращает параметры stmtInsertURL для record.
+func recordArgs(record entities.URLRecord) ([]any, error) {
+	p := record.Redirect
+
+	allowlist := p.QueryAllowlist
+	if allowlist == nil {
+		allowlist = []string{}
+	}
+	allowlistJSON, err := json.Marshal(allowlist)
+	if err != nil {
+		return nil, err
+	}
+
+	params := p.DefaultParams
+	if params == nil {
+		params = map[string]string{}
+	}
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := record.Rules
+	if rules == nil {
+		rules = []entities.Rule{}
+	}
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return []any{
+		record.Short,
+		record.OriginalURL,
+		record.Scope,
+		record.Dedupe,
+		p.Code,
+		p.CacheMaxAge,
+		p.ReferrerPolicy,
+		string(p.QueryMode),
+		string(allowlistJSON),
+		string(paramsJSON),
+		p.Prefix,
+		string(rulesJSON),
+	}, nil
+}
+
+// DBOptions — параметры пула соединений и отказоустойчивости DBRepository.
+type DBOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// попытки подключиться и применить миграции при старте, пауза между ними удваивается
+	ConnectAttempts int
+	ConnectBackoff  time.Duration
+	// применять ли миграции при старте; без них старт падает, если схема устарела
+	AutoMigrate bool
+	// сколько ждать advisory-лок, пока миграции применяет другой экземпляр
+	MigrationLockTimeout time.Duration
+
+	// таймаут одного обращения к базе; 0 — без ограничения
+	QueryTimeout time.Duration
+	// сколько раз повторить запрос после временной ошибки
+	QueryRetries int
+
+	// отказов подряд, после которых запросы отклоняются без обращения к базе; 0 — выключено
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// DSN реплик только для чтения, на них уходят Get и GetRecord
+	Replicas             []string
+	ReplicaCheckInterval time.Duration
+	// сколько после записи id читается с primary, чтобы не отстать от своей же записи
+	ReadYourWritesWindow time.Duration
+}
+
+func DefaultDBOptions() DBOptions {
+	return DBOptions{
+		MaxOpenConns:         25,
+		MaxIdleConns:         10,
+		ConnMaxLifetime:      30 * time.Minute,
+		ConnMaxIdleTime:      5 * time.Minute,
+		ConnectAttempts:      5,
+		ConnectBackoff:       500 * time.Millisecond,
+		AutoMigrate:          true,
+		MigrationLockTimeout: 5 * time.Minute,
+		QueryTimeout:         3 * time.Second,
+		QueryRetries:         2,
+		BreakerThreshold:     5,
+		BreakerCooldown:      10 * time.Second,
+
+		ReplicaCheckInterval: 5 * time.Second,
+		ReadYourWritesWindow: 5 * time.Second,
+	}
 }
 
 type DBRepository struct {
 	DB  *sql.DB
 	DSN string
+
+	guard
+	// nil, если реплики не заданы
+	replicas *replicaSet
 }
 
-func NewDBRepository(DSN string) (*DBRepository, error) {
-	error := applyMigrations(DSN)
-	if error != nil {
-		return nil, error
+// NewDBRepository открывает пул, дожидается базы и применяет миграции.
+// Недоступная при старте база опрашивается opts.ConnectAttempts раз с
+// экспоненциальной паузой; если она так и не ответила, возвращается ошибка.
+// Реплики из opts.Replicas подключаются после primary и на старт не влияют.
+func NewDBRepository(ctx context.Context, DSN string, opts DBOptions) (*DBRepository, error) {
+	db, err := sql.Open("pgx", DSN)
+	if err != nil {
+		return nil, err
 	}
 
-	db, error := sql.Open("pgx", DSN)
-	if error != nil {
-		return nil, error
-	}
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
 
 	repo := DBRepository{
-		DB:  db,
-		DSN: DSN,
+		DB:    db,
+		DSN:   DSN,
+		guard: newGuard(opts),
 	}
 
+	if err := repo.connect(ctx, DSN, db.PingContext); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if len(opts.Replicas) > 0 {
+		repo.replicas, err = newReplicaSet(ctx, opts.Replicas, opts)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
 	return &repo, nil
 }
+
+// Close останавливает проверку реплик и закрывает все пулы.
+func (repo *DBRepository) Close() error {
+	repo.replicas.close()
+	return repo.DB.Close()
+}
+
 func (repo *DBRepository) Ping(ctx context.Context) bool {
-	err := repo.DB.PingContext(ctx)
-	return err == nil
+	return repo.run(ctx, repo.DB.PingContext) == nil
 }
 
-func (repo *DBRepository) Save(ctx context.Context, id string, url string) (string, error) {
-	var returnedShort string
-	err := repo.DB.QueryRowContext(
-		ctx,
-		`INSERT INTO urls(short, original) VALUES ($1, $2)
-		ON CONFLICT(original) DO UPDATE 
-		SET original = excluded.original RETURNING short`,
-		id,
-		url,
-	).Scan(&returnedShort)
+// read выполняет чтение id на исправной реплике, если id не записывался только
+// что, иначе на primary. Любая ошибка реплики, в том числе отсутствие записи
+// (реплика могла еще не догнать primary), повторяет чтение на primary.
+func (repo *DBRepository) read(ctx context.Context, id string, op func(ctx context.Context, db *sql.DB) error) error {
+	if r := repo.replicas.pick(id); r != nil {
+		err := repo.attempt(ctx, func(ctx context.Context) error {
+			return op(ctx, r.db)
+		})
+		if err == nil {
+			metrics.DBReads.WithLabelValues("replica").Inc()
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		if isOutage(err) {
+			r.setHealthy(false)
+		}
+	}
+
+	metrics.DBReads.WithLabelValues("primary").Inc()
+	return repo.run(ctx, func(ctx context.Context) error {
+		return op(ctx, repo.DB)
+	})
+}
+
+// querier — общее у *sql.DB и *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
 
+func insertVariants(ctx context.Context, q querier, short string, variants []entities.Variant) error {
+	for i, v := range variants {
+		_, err := q.ExecContext(
+			ctx,
+			stmtInsertVariant.sql,
+			short, i, v.Name, v.URL, v.Weight, v.Clicks,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repo *DBRepository) Save(ctx context.Context, record entities.URLRecord) (string, error) {
+	var short string
+	err := repo.run(ctx, func(ctx context.Context) (err error) {
+		if len(record.Variants) == 0 {
+			short, err = save(ctx, repo.DB, record)
+			return err
+		}
+
+		tx, err := repo.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		short, err = save(ctx, tx, record)
+		if err == nil && short == record.Short {
+			err = insertVariants(ctx, tx, short, record.Variants)
+		}
+		if err != nil {
+			tx.Rollback()
+			return translateError(err)
+		}
+
+		return tx.Commit()
+	})
 	if err != nil {
 		return "", err
 	}
+
+	repo.replicas.written(short)
+	return short, nil
+}
+
+func save(ctx context.Context, q querier, record entities.URLRecord) (string, error) {
+	args, err := recordArgs(record)
+	if err != nil {
+		return "", err
+	}
+
+	var returnedShort string
+	err = q.QueryRowContext(ctx, stmtSaveURL.sql, args...).Scan(&returnedShort)
+
+	if err != nil {
+		return "", translateError(err)
+	}
 	return returnedShort, nil
 }
 
 func (repo *DBRepository) Get(ctx context.Context, id string) (string, bool) {
 	var fullURL string
 
-	row := repo.DB.QueryRowContext(ctx, "SELECT original FROM urls WHERE short=$1", id)
-	err := row.Scan(&fullURL)
-
+	err := repo.read(ctx, id, func(ctx context.Context, db *sql.DB) error {
+		return db.QueryRowContext(ctx, stmtGetOriginal.sql, id).Scan(&fullURL)
+	})
 	if err != nil {
 		return "", false
 	}
@@ -86,19 +295,233 @@ func (repo *DBRepository) Get(ctx context.Context, id string) (string, bool) {
 	return fullURL, true
 }
 
-func (repo *DBRepository) BatchSave(ctx context.Context, records []entities.URLRecord) error {
-	tx, err := repo.DB.BeginTx(ctx, nil)
+func (repo *DBRepository) GetRecord(ctx context.Context, id string) (entities.URLRecord, error) {
+	var record entities.URLRecord
+
+	err := repo.read(ctx, id, func(ctx context.Context, db *sql.DB) (err error) {
+		record, err = getRecord(ctx, db, stmtGetRecord.sql, id)
+		return err
+	})
 	if err != nil {
+		return entities.URLRecord{}, err
+	}
+	return record, nil
+}
+
+// getRecord читает запись запросом query с колонками stmtGetRecord; отсутствующий id — ErrNotFound.
+func getRecord(ctx context.Context, q querier, query string, id string) (entities.URLRecord, error) {
+	var (
+		record        entities.URLRecord
+		allowlistJSON []byte
+		paramsJSON    []byte
+		rulesJSON     []byte
+		variantsJSON  []byte
+	)
+
+	err := q.QueryRowContext(ctx, query, id).Scan(
+		&record.Short,
+		&record.OriginalURL,
+		&record.CreatedAt,
+		&record.Scope,
+		&record.Dedupe,
+		&record.Redirect.Code,
+		&record.Redirect.CacheMaxAge,
+		&record.Redirect.ReferrerPolicy,
+		&record.Redirect.QueryMode,
+		&allowlistJSON,
+		&paramsJSON,
+		&record.Redirect.Prefix,
+		&rulesJSON,
+		&variantsJSON,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entities.URLRecord{}, ErrNotFound
+	}
+	if err != nil {
+		return entities.URLRecord{}, err
+	}
+
+	if err := decodeRecord(&record, allowlistJSON, paramsJSON, rulesJSON, variantsJSON); err != nil {
+		return entities.URLRecord{}, err
+	}
+	return record, nil
+}
+
+// decodeRecord раскладывает JSON-колонки stmtGetRecord по полям record.
+func decodeRecord(record *entities.URLRecord, allowlistJSON, paramsJSON, rulesJSON, variantsJSON []byte) error {
+	if err := json.Unmarshal(allowlistJSON, &record.Redirect.QueryAllowlist); err != nil {
 		return err
 	}
+	if err := json.Unmarshal(paramsJSON, &record.Redirect.DefaultParams); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(rulesJSON, &record.Rules); err != nil {
+		return err
+	}
+	return json.Unmarshal(variantsJSON, &record.Variants)
+}
 
-	for _, r := range records {
-		_, err := tx.ExecContext(ctx, "INSERT INTO urls(short, original) VALUES ($1, $2)", r.Short, r.OriginalURL)
+// exec выполняет изменяющий запрос и возвращает ErrNotFound, если он не затронул ни одной строки.
+func (repo *DBRepository) exec(ctx context.Context, query string, args ...any) error {
+	return repo.run(ctx, func(ctx context.Context) error {
+		result, err := repo.DB.ExecContext(ctx, query, args...)
 		if err != nil {
-			tx.Rollback()
 			return err
 		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (repo *DBRepository) SetRules(ctx context.Context, id string, rules []entities.Rule) error {
+	if rules == nil {
+		rules = []entities.Rule{}
+	}
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+
+	if err := repo.exec(ctx, stmtSetRules.sql, id, string(rulesJSON)); err != nil {
+		return err
+	}
+
+	repo.replicas.written(id)
+	return nil
+}
+
+func (repo *DBRepository) SetVariants(ctx context.Context, id string, variants []entities.Variant) error {
+	err := repo.run(ctx, func(ctx context.Context) error {
+		return repo.setVariants(ctx, id, variants)
+	})
+	if err != nil {
+		return err
+	}
+
+	repo.replicas.written(id)
+	return nil
+}
+
+func (repo *DBRepository) setVariants(ctx context.Context, id string, variants []entities.Variant) error {
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, stmtLockURL.sql, id).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, stmtVariantClicks.sql, id)
+	if err != nil {
+		return err
+	}
+	var old []entities.Variant
+	for rows.Next() {
+		var v entities.Variant
+		if err := rows.Scan(&v.Name, &v.Clicks); err != nil {
+			rows.Close()
+			return err
+		}
+		old = append(old, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, stmtDeleteVariants.sql, id); err != nil {
+		return err
+	}
+	if err := insertVariants(ctx, tx, id, carryClicks(old, variants)); err != nil {
+		return err
 	}
 
 	return tx.Commit()
 }
+
+func (repo *DBRepository) IncrementClicks(ctx context.Context, id string, variant string) error {
+	return repo.exec(ctx, stmtIncrementClicks.sql, id, variant)
+}
+
+func (repo *DBRepository) BatchSave(ctx context.Context, records []entities.URLRecord) ([]string, error) {
+	shorts, _, err := repo.batchSave(ctx, records)
+	return shorts, err
+}
+
+// batchSave сохраняет пачку одной транзакцией. Кроме short записей возвращает,
+// какие из них вставлены сейчас: ShardedRepository при откате удаляет только их.
+func (repo *DBRepository) batchSave(ctx context.Context, records []entities.URLRecord) ([]string, []bool, error) {
+	var (
+		shorts   []string
+		inserted []bool
+	)
+	err := repo.run(ctx, func(ctx context.Context) error {
+		tx, err := repo.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		shorts, inserted, err = insertRecords(ctx, tx, records)
+		if err != nil {
+			return translateError(err)
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	repo.replicas.written(shorts...)
+	return shorts, inserted, nil
+}
+
+// insertRecords вставляет записи пачки в транзакции tx. Если short или адрес
+// записи с Dedupe уже заняты, запись не вставляется, а возвращается short
+// существующей (stmtFindExisting); занятый другой записью short — ErrAlreadyExists.
+// inserted[i] — вставлена ли records[i] сейчас.
+func insertRecords(ctx context.Context, tx querier, records []entities.URLRecord) ([]string, []bool, error) {
+	shorts := make([]string, len(records))
+	inserted := make([]bool, len(records))
+	for i, r := range records {
+		args, err := recordArgs(r)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		err = tx.QueryRowContext(ctx, stmtInsertURLIfAbsent.sql, args...).Scan(&shorts[i])
+		if errors.Is(err, sql.ErrNoRows) {
+			err = tx.QueryRowContext(ctx, stmtFindExisting.sql, r.Short, r.OriginalURL, r.Scope, r.Dedupe).Scan(&shorts[i])
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, nil, ErrAlreadyExists
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := insertVariants(ctx, tx, r.Short, r.Variants); err != nil {
+			return nil, nil, err
+		}
+		inserted[i] = true
+	}
+	return shorts, inserted, nil
+}
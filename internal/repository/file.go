@@ -1,30 +1,62 @@
 package repository
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"os"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/metrics"
 )
 
 type record struct {
-	UUID        string `json:"uuid"`
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
+	UUID           string    `json:"uuid"`
+	ShortURL       string    `json:"short_url"`
+	OriginalURL    string    `json:"original_url"`
+	CreatedAt      time.Time `json:"created_at,omitzero"`
+	RedirectCode   int       `json:"redirect_code,omitempty"`
+	CacheMaxAge    *int      `json:"cache_max_age,omitempty"`
+	ReferrerPolicy string    `json:"referrer_policy,omitempty"`
+
+	QueryMode      entities.QueryMode `json:"query_mode,omitempty"`
+	QueryAllowlist []string           `json:"query_allowlist,omitempty"`
+	DefaultParams  map[string]string  `json:"default_params,omitempty"`
+	Prefix         bool               `json:"prefix,omitempty"`
+
+	Rules    []entities.Rule    `json:"rules,omitempty"`
+	Variants []entities.Variant `json:"variants,omitempty"`
+
+	Scope string `json:"scope,omitempty"`
+	// в старых файлах поля нет, их записи участвуют в дедупликации
+	NoDedupe bool `json:"no_dedupe,omitempty"`
 }
 
+// clicksFlushInterval — как часто накопленные клики сбрасываются в файл.
+var clicksFlushInterval = time.Second
+
 type FileRepository struct {
 	memoryRepo *MemoryRepository
 	path       string
 	mu         sync.Mutex
+	// клики меняются на каждом переходе, поэтому файл с ними переписывается не сразу,
+	// а в flushLoop, при следующей записи или в Close
+	clicksDirty atomic.Bool
+	stop        chan struct{}
+	done        chan struct{}
+	closeOnce   sync.Once
 }
 
 func NewFileRepository(ctx context.Context, fileStoragePath string) (*FileRepository, error) {
 	repo := &FileRepository{
 		memoryRepo: NewMemoryRepository(),
 		path:       fileStoragePath,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
 	}
 
 	err := repo.loadDataFromFile(ctx)
@@ -32,9 +64,46 @@ func NewFileRepository(ctx context.Context, fileStoragePath string) (*FileReposi
 		return nil, err
 	}
 
+	go repo.flushLoop(clicksFlushInterval)
 	return repo, nil
 }
 
+func (repo *FileRepository) flushLoop(interval time.Duration) {
+	defer close(repo.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-repo.stop:
+			return
+		case <-ticker.C:
+			repo.Flush()
+		}
+	}
+}
+
+// Flush записывает в файл клики, накопленные после последней записи.
+func (repo *FileRepository) Flush() error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if !repo.clicksDirty.Load() {
+		return nil
+	}
+	return repo.saveToFile()
+}
+
+// Close останавливает фоновый сброс кликов и записывает оставшиеся.
+func (repo *FileRepository) Close() error {
+	repo.closeOnce.Do(func() {
+		close(repo.stop)
+		<-repo.done
+	})
+	return repo.Flush()
+}
+
 func (repo *FileRepository) loadDataFromFile(ctx context.Context) error {
 	bytes, err := os.ReadFile(repo.path)
 
@@ -50,30 +119,83 @@ func (repo *FileRepository) loadDataFromFile(ctx context.Context) error {
 		return err
 	}
 
+	// в индекс original→short попадает самая ранняя запись, как и при работе без перезапуска
+	slices.SortStableFunc(records, compareRecords)
+
 	for _, r := range records {
-		repo.memoryRepo.Save(ctx, r.ShortURL, r.OriginalURL)
+		repo.memoryRepo.put(entities.URLRecord{
+			OriginalURL: r.OriginalURL,
+			Short:       r.ShortURL,
+			CreatedAt:   r.CreatedAt,
+			Redirect: entities.RedirectPolicy{
+				Code:           r.RedirectCode,
+				CacheMaxAge:    r.CacheMaxAge,
+				ReferrerPolicy: r.ReferrerPolicy,
+				QueryMode:      r.QueryMode,
+				QueryAllowlist: r.QueryAllowlist,
+				DefaultParams:  r.DefaultParams,
+				Prefix:         r.Prefix,
+			},
+			Rules:    r.Rules,
+			Variants: r.Variants,
+			Scope:    r.Scope,
+			Dedupe:   !r.NoDedupe,
+		})
 	}
 	return nil
 }
 
+// compareRecords упорядочивает записи по времени создания, затем по short.
+func compareRecords(a, b record) int {
+	return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ShortURL, b.ShortURL))
+}
+
 func (repo *FileRepository) saveToFile() error {
+	start := time.Now()
+	defer func() {
+		metrics.FileWriteDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	// снимок включает все клики, учтенные до этого момента; более поздние снова выставят флаг
+	repo.clicksDirty.Store(false)
+
+	repo.memoryRepo.mu.RLock()
 	records := make([]record, 0, len(repo.memoryRepo.data))
-	for short, original := range repo.memoryRepo.data {
+	for short, r := range repo.memoryRepo.data {
 		records = append(records, record{
-			UUID:        short,
-			ShortURL:    short,
-			OriginalURL: original,
+			UUID:           short,
+			ShortURL:       short,
+			OriginalURL:    r.OriginalURL,
+			CreatedAt:      r.CreatedAt,
+			RedirectCode:   r.Redirect.Code,
+			CacheMaxAge:    r.Redirect.CacheMaxAge,
+			ReferrerPolicy: r.Redirect.ReferrerPolicy,
+			QueryMode:      r.Redirect.QueryMode,
+			QueryAllowlist: r.Redirect.QueryAllowlist,
+			DefaultParams:  r.Redirect.DefaultParams,
+			Prefix:         r.Redirect.Prefix,
+			Rules:          r.Rules,
+			Variants:       r.Variants,
+			Scope:          r.Scope,
+			NoDedupe:       !r.Dedupe,
 		})
 	}
+	repo.memoryRepo.mu.RUnlock()
+
+	slices.SortFunc(records, compareRecords)
 
 	bytes, err := json.MarshalIndent(records, "", "  ")
+	if err == nil {
+		err = os.WriteFile(repo.path, bytes, 0644)
+	}
 	if err != nil {
-		return err
+		// неудачную запись повторит flushLoop
+		repo.clicksDirty.Store(true)
 	}
-
-	return os.WriteFile(repo.path, bytes, 0644)
+	return err
 }
-func (repo *FileRepository) Save(ctx context.Context, id string, url string) (string, error) {
+
+func (repo *FileRepository) Save(ctx context.Context, record entities.URLRecord) (string, error) {
 	select {
 	case <-ctx.Done():
 		return "", ctx.Err()
@@ -83,20 +205,53 @@ func (repo *FileRepository) Save(ctx context.Context, id string, url string) (st
 	repo.mu.Lock()
 	defer repo.mu.Unlock()
 
-	_, exists := repo.memoryRepo.Get(ctx, id)
-	if exists {
-		return "", ErrAlreadyExists
+	id, err := repo.memoryRepo.Save(ctx, record)
+	if err != nil || id != record.Short {
+		return id, err
 	}
 
-	id, err := repo.memoryRepo.Save(ctx, id, url)
+	return id, repo.saveToFile()
+}
+
+func (repo *FileRepository) BatchSave(ctx context.Context, records []entities.URLRecord) ([]string, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	shorts, err := repo.memoryRepo.BatchSave(ctx, records)
 	if err != nil {
-		return id, err
+		return nil, err
 	}
 
-	return id, repo.saveToFile()
+	return shorts, repo.saveToFile()
 }
 
-func (repo *FileRepository) BatchSave(ctx context.Context, records []entities.URLRecord) error {
+func (repo *FileRepository) Get(ctx context.Context, id string) (string, bool) {
+	select {
+	case <-ctx.Done():
+		return "", false
+	default:
+	}
+
+	return repo.memoryRepo.Get(ctx, id)
+}
+
+func (repo *FileRepository) GetRecord(ctx context.Context, id string) (entities.URLRecord, error) {
+	select {
+	case <-ctx.Done():
+		return entities.URLRecord{}, ctx.Err()
+	default:
+	}
+
+	return repo.memoryRepo.GetRecord(ctx, id)
+}
+
+func (repo *FileRepository) SetRules(ctx context.Context, id string, rules []entities.Rule) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -106,21 +261,44 @@ func (repo *FileRepository) BatchSave(ctx context.Context, records []entities.UR
 	repo.mu.Lock()
 	defer repo.mu.Unlock()
 
-	if err := repo.memoryRepo.BatchSave(ctx, records); err != nil {
+	if err := repo.memoryRepo.SetRules(ctx, id, rules); err != nil {
 		return err
 	}
 
 	return repo.saveToFile()
 }
 
-func (repo *FileRepository) Get(ctx context.Context, id string) (string, bool) {
+func (repo *FileRepository) SetVariants(ctx context.Context, id string, variants []entities.Variant) error {
 	select {
 	case <-ctx.Done():
-		return "", false
+		return ctx.Err()
 	default:
 	}
 
-	return repo.memoryRepo.Get(ctx, id)
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if err := repo.memoryRepo.SetVariants(ctx, id, variants); err != nil {
+		return err
+	}
+
+	return repo.saveToFile()
+}
+
+func (repo *FileRepository) IncrementClicks(ctx context.Context, id string, variant string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	// файл не переписывается на каждый переход: клик попадет в него при сбросе
+	if err := repo.memoryRepo.IncrementClicks(ctx, id, variant); err != nil {
+		return err
+	}
+
+	repo.clicksDirty.Store(true)
+	return nil
 }
 
 func (repo *FileRepository) Ping(ctx context.Context) bool {
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileClicksAreFlushedLater(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+
+	// фоновый сброс не должен успеть сработать посреди теста
+	interval := clicksFlushInterval
+	clicksFlushInterval = time.Hour
+	t.Cleanup(func() { clicksFlushInterval = interval })
+
+	repo, err := NewFileRepository(t.Context(), path)
+	require.NoError(t, err)
+
+	_, err = repo.Save(t.Context(), entities.URLRecord{
+		Short:       "ab",
+		OriginalURL: "https://example.com",
+		Variants:    []entities.Variant{{Name: "a", URL: "https://example.com/a", Weight: 1}},
+	})
+	require.NoError(t, err)
+	saved, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.IncrementClicks(t.Context(), "ab", "a"))
+	require.NoError(t, repo.IncrementClicks(t.Context(), "ab", "a"))
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(saved), string(current), "a click must not rewrite the file")
+
+	require.NoError(t, repo.Close())
+
+	repo, err = NewFileRepository(t.Context(), path)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	record, err := repo.GetRecord(t.Context(), "ab")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), record.Variants[0].Clicks)
+}
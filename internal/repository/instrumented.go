@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/metrics"
+	"github.com/Oleg2210/goshortener/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentedRepository замеряет длительность каждой операции вложенного репозитория
+// и открывает на нее спан трассировки.
+type InstrumentedRepository struct {
+	repo    URLRepository
+	backend string
+}
+
+func NewInstrumentedRepository(repo URLRepository, backend string) *InstrumentedRepository {
+	return &InstrumentedRepository{
+		repo:    repo,
+		backend: backend,
+	}
+}
+
+func (ir *InstrumentedRepository) start(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return tracing.Start(
+		ctx,
+		"repository."+operation,
+		attribute.String("db.system", ir.backend),
+		attribute.String("db.operation", operation),
+	)
+}
+
+func (ir *InstrumentedRepository) observe(operation string, start time.Time) {
+	metrics.StorageDuration.WithLabelValues(ir.backend, operation).Observe(time.Since(start).Seconds())
+}
+
+func (ir *InstrumentedRepository) Save(ctx context.Context, record entities.URLRecord) (string, error) {
+	defer ir.observe("save", time.Now())
+	ctx, span := ir.start(ctx, "save")
+
+	short, err := ir.repo.Save(ctx, record)
+	tracing.End(span, err)
+	return short, err
+}
+
+func (ir *InstrumentedRepository) BatchSave(ctx context.Context, records []entities.URLRecord) ([]string, error) {
+	defer ir.observe("batch_save", time.Now())
+	ctx, span := ir.start(ctx, "batch_save")
+	span.SetAttributes(attribute.Int("batch.size", len(records)))
+
+	shorts, err := ir.repo.BatchSave(ctx, records)
+	tracing.End(span, err)
+	return shorts, err
+}
+
+func (ir *InstrumentedRepository) Get(ctx context.Context, id string) (string, bool) {
+	defer ir.observe("get", time.Now())
+	ctx, span := ir.start(ctx, "get")
+
+	url, exists := ir.repo.Get(ctx, id)
+	span.SetAttributes(attribute.Bool("found", exists))
+	tracing.End(span, nil)
+	return url, exists
+}
+
+func (ir *InstrumentedRepository) GetRecord(ctx context.Context, id string) (entities.URLRecord, error) {
+	defer ir.observe("get_record", time.Now())
+	ctx, span := ir.start(ctx, "get_record")
+
+	record, err := ir.repo.GetRecord(ctx, id)
+	span.SetAttributes(attribute.Bool("found", err == nil))
+	// отсутствие записи — обычный ответ, а не ошибка операции
+	if errors.Is(err, ErrNotFound) {
+		tracing.End(span, nil)
+	} else {
+		tracing.End(span, err)
+	}
+	return record, err
+}
+
+func (ir *InstrumentedRepository) SetRules(ctx context.Context, id string, rules []entities.Rule) error {
+	defer ir.observe("set_rules", time.Now())
+	ctx, span := ir.start(ctx, "set_rules")
+	span.SetAttributes(attribute.Int("rules.count", len(rules)))
+
+	err := ir.repo.SetRules(ctx, id, rules)
+	tracing.End(span, err)
+	return err
+}
+
+func (ir *InstrumentedRepository) SetVariants(ctx context.Context, id string, variants []entities.Variant) error {
+	defer ir.observe("set_variants", time.Now())
+	ctx, span := ir.start(ctx, "set_variants")
+	span.SetAttributes(attribute.Int("variants.count", len(variants)))
+
+	err := ir.repo.SetVariants(ctx, id, variants)
+	tracing.End(span, err)
+	return err
+}
+
+func (ir *InstrumentedRepository) IncrementClicks(ctx context.Context, id string, variant string) error {
+	defer ir.observe("increment_clicks", time.Now())
+	ctx, span := ir.start(ctx, "increment_clicks")
+
+	err := ir.repo.IncrementClicks(ctx, id, variant)
+	tracing.End(span, err)
+	return err
+}
+
+func (ir *InstrumentedRepository) Ping(ctx context.Context) bool {
+	defer ir.observe("ping", time.Now())
+	ctx, span := ir.start(ctx, "ping")
+
+	pinged := ir.repo.Ping(ctx)
+	span.SetAttributes(attribute.Bool("pinged", pinged))
+	tracing.End(span, nil)
+	return pinged
+}
+
+// Close закрывает вложенный репозиторий, если ему есть что закрывать.
+func (ir *InstrumentedRepository) Close() error {
+	if closer, ok := ir.repo.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package repository_test
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+)
+
+// postgresDSN — база для прогона набора repositorytest против DBRepository.
+var postgresDSN string
+
+// TestMain поднимает Postgres для тестов: внешний из TEST_DATABASE_DSN или,
+// при EMBEDDED_POSTGRES=1, локальный экземпляр без докера. Бинарники
+// embedded-postgres скачиваются при первом запуске и кешируются; под root
+// initdb не запускается.
+func TestMain(m *testing.M) {
+	postgresDSN = os.Getenv("TEST_DATABASE_DSN")
+	if postgresDSN != "" || os.Getenv("EMBEDDED_POSTGRES") == "" {
+		os.Exit(m.Run())
+	}
+
+	stop, err := startEmbeddedPostgres()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "embedded postgres: %v\n", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	stop()
+	os.Exit(code)
+}
+
+func startEmbeddedPostgres() (func(), error) {
+	dir, err := os.MkdirTemp("", "goshortener-pg-")
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	config := embeddedpostgres.DefaultConfig().
+		Port(port).
+		RuntimePath(dir + "/runtime").
+		DataPath(dir + "/data").
+		Logger(nil)
+	pg := embeddedpostgres.NewDatabase(config)
+	if err := pg.Start(); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	postgresDSN = config.GetConnectionURL() + "?sslmode=disable"
+	return func() {
+		pg.Stop()
+		os.RemoveAll(dir)
+	}, nil
+}
+
+func freePort() (uint32, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return uint32(l.Addr().(*net.TCPAddr).Port), nil
+}
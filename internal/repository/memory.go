@@ -2,66 +2,215 @@ package repository
 
 import (
 	"context"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/Oleg2210/goshortener/internal/entities"
 )
 
 type MemoryRepository struct {
-	data map[string]string
+	// счетчики кликов меняются на каждом переходе, поэтому доступ к data защищен
+	mu   sync.RWMutex
+	data map[string]entities.URLRecord
+	// индекс original→short для записей с Dedupe, первая запись остается в нем навсегда
+	originals map[entities.DedupeKey]string
 }
 
 func NewMemoryRepository() *MemoryRepository {
 	repo := &MemoryRepository{
-		data: make(map[string]string),
+		data:      make(map[string]entities.URLRecord),
+		originals: make(map[entities.DedupeKey]string),
 	}
 
 	return repo
 }
 
-func (repo *MemoryRepository) Save(ctx context.Context, id string, url string) (string, error) {
+// put кладет запись как есть, сохраняя ее CreatedAt. Вызывается под mu.
+func (repo *MemoryRepository) put(record entities.URLRecord) {
+	repo.data[record.Short] = record
+
+	if !record.Dedupe {
+		return
+	}
+	if _, exists := repo.originals[record.DedupeKey()]; !exists {
+		repo.originals[record.DedupeKey()] = record.Short
+	}
+}
+
+func (repo *MemoryRepository) Save(ctx context.Context, record entities.URLRecord) (string, error) {
 	select {
 	case <-ctx.Done():
 		return "", ctx.Err()
 	default:
 	}
 
-	if _, exists := repo.data[id]; exists {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if record.Dedupe {
+		if short, exists := repo.originals[record.DedupeKey()]; exists {
+			return short, nil
+		}
+	}
+	if _, exists := repo.data[record.Short]; exists {
 		return "", ErrAlreadyExists
 	}
 
-	repo.data[id] = url
-	return id, nil
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now().UTC()
+	}
+	repo.put(record)
+	return record.Short, nil
 }
 
-func (repo *MemoryRepository) BatchSave(ctx context.Context, records []entities.URLRecord) error {
+func (repo *MemoryRepository) BatchSave(ctx context.Context, records []entities.URLRecord) ([]string, error) {
 	select {
 	case <-ctx.Done():
-		return ctx.Err()
+		return nil, ctx.Err()
 	default:
 	}
 
-	for _, r := range records {
-		if _, exists := repo.data[r.Short]; exists {
-			return ErrAlreadyExists
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	// как и уникальные индексы в БД, конфликт внутри пачки тоже считается конфликтом.
+	// Запись с тем же short и адресом уже сохранена, если пачку повторяют после обрыва.
+	result := make([]string, len(records))
+	shorts := make(map[string]string, len(records))
+	originals := make(map[entities.DedupeKey]string)
+	fresh := make([]entities.URLRecord, 0, len(records))
+	for i, r := range records {
+		if r.Dedupe {
+			if short, exists := repo.originals[r.DedupeKey()]; exists {
+				result[i] = short
+				continue
+			}
+			if short, exists := originals[r.DedupeKey()]; exists {
+				result[i] = short
+				continue
+			}
+		}
+
+		result[i] = r.Short
+		if stored, exists := repo.data[r.Short]; exists {
+			if stored.OriginalURL != r.OriginalURL {
+				return nil, ErrAlreadyExists
+			}
+			continue
+		}
+		if original, exists := shorts[r.Short]; exists {
+			if original != r.OriginalURL {
+				return nil, ErrAlreadyExists
+			}
+			continue
+		}
+		shorts[r.Short] = r.OriginalURL
+		fresh = append(fresh, r)
+
+		if r.Dedupe {
+			originals[r.DedupeKey()] = r.Short
 		}
 	}
 
-	for _, r := range records {
-		repo.data[r.Short] = r.OriginalURL
+	now := time.Now().UTC()
+	for _, r := range fresh {
+		if r.CreatedAt.IsZero() {
+			r.CreatedAt = now
+		}
+		repo.put(r)
 	}
 
-	return nil
+	return result, nil
 }
 
 func (repo *MemoryRepository) Get(ctx context.Context, id string) (string, bool) {
+	record, err := repo.GetRecord(ctx, id)
+	return record.OriginalURL, err == nil
+}
+
+func (repo *MemoryRepository) GetRecord(ctx context.Context, id string) (entities.URLRecord, error) {
 	select {
 	case <-ctx.Done():
-		return "", false
+		return entities.URLRecord{}, ctx.Err()
 	default:
 	}
 
-	url, exists := repo.data[id]
-	return url, exists
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	record, exists := repo.data[id]
+	if !exists {
+		return entities.URLRecord{}, ErrNotFound
+	}
+	return record, nil
+}
+
+func (repo *MemoryRepository) SetRules(ctx context.Context, id string, rules []entities.Rule) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	record, exists := repo.data[id]
+	if !exists {
+		return ErrNotFound
+	}
+
+	record.Rules = rules
+	repo.put(record)
+	return nil
+}
+
+func (repo *MemoryRepository) SetVariants(ctx context.Context, id string, variants []entities.Variant) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	record, exists := repo.data[id]
+	if !exists {
+		return ErrNotFound
+	}
+
+	record.Variants = carryClicks(record.Variants, variants)
+	repo.put(record)
+	return nil
+}
+
+func (repo *MemoryRepository) IncrementClicks(ctx context.Context, id string, variant string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	record, exists := repo.data[id]
+	if !exists {
+		return ErrNotFound
+	}
+
+	i := slices.IndexFunc(record.Variants, func(v entities.Variant) bool { return v.Name == variant })
+	if i < 0 {
+		return ErrNotFound
+	}
+
+	// срез копируется: прежний мог уйти наружу из GetRecord
+	record.Variants = slices.Clone(record.Variants)
+	record.Variants[i].Clicks++
+	repo.put(record)
+	return nil
 }
 
 func (repo *MemoryRepository) Ping(ctx context.Context) bool {
@@ -71,5 +220,20 @@ func (repo *MemoryRepository) Ping(ctx context.Context) bool {
 	default:
 	}
 
-	return false
+	return true
+}
+
+// carryClicks переносит счетчики кликов из old в одноименные варианты next.
+func carryClicks(old, next []entities.Variant) []entities.Variant {
+	result := make([]entities.Variant, len(next))
+	for i, v := range next {
+		v.Clicks = 0
+		for _, o := range old {
+			if o.Name == v.Name {
+				v.Clicks = o.Clicks
+			}
+		}
+		result[i] = v
+	}
+	return result
 }
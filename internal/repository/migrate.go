@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"time"
+
+	"github.com/Oleg2210/goshortener/migrations"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// ErrSchemaOutdated — схема базы не совпадает с миграциями, встроенными в бинарник.
+var ErrSchemaOutdated = errors.New("database schema is outdated")
+
+// Migration — встроенная миграция и ее состояние в базе.
+type Migration struct {
+	Version uint
+	Name    string
+	Applied bool
+}
+
+// Migrator применяет к базе миграции из пакета migrations. Up и Down выполняются
+// под advisory-локом Postgres, который берет драйвер golang-migrate, поэтому
+// одновременно стартующие экземпляры применяют миграции по очереди; лок
+// ожидается не дольше lockTimeout.
+// Для sqlite:// DSN берутся миграции на диалекте SQLite.
+type Migrator struct {
+	m    *migrate.Migrate
+	fsys fs.FS
+}
+
+func NewMigrator(dsn string, lockTimeout time.Duration) (*Migrator, error) {
+	var fsys fs.FS = migrations.FS
+	if IsSQLiteDSN(dsn) {
+		sub, err := fs.Sub(migrations.SQLite, "sqlite")
+		if err != nil {
+			return nil, err
+		}
+		fsys = sub
+	}
+
+	src, err := iofs.New(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", src, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if lockTimeout > 0 {
+		m.LockTimeout = lockTimeout
+	}
+
+	return &Migrator{m: m, fsys: fsys}, nil
+}
+
+func (mg *Migrator) Close() error {
+	srcErr, dbErr := mg.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+// Up применяет все еще не примененные миграции.
+func (mg *Migrator) Up() error {
+	err := mg.m.Up()
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// Down откатывает steps последних примененных миграций. Миграции выполняются
+// в транзакции, поэтому упавшая оставляет схему прежней; версия тогда
+// возвращается к ней, а не остается dirty.
+func (mg *Migrator) Down(steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("steps must be positive, got %d", steps)
+	}
+
+	// миграций меньше, чем steps: откачены все, это не ошибка
+	for range steps {
+		version, _, err := mg.Version()
+		if err != nil || version == 0 {
+			return err
+		}
+
+		err = mg.m.Steps(-1)
+		if errors.Is(err, migrate.ErrNoChange) {
+			return nil
+		}
+		if err != nil {
+			return mg.restore(version, err)
+		}
+	}
+	return nil
+}
+
+// restore возвращает версию version, если откат с нее упал и пометил схему dirty.
+func (mg *Migrator) restore(version uint, err error) error {
+	current, dirty, vErr := mg.Version()
+	if vErr != nil || !dirty || current >= version {
+		return err
+	}
+	return errors.Join(err, mg.m.Force(int(version)))
+}
+
+// Version возвращает версию схемы; 0 — ни одна миграция не применялась.
+// dirty — последняя миграция упала на середине и схему нужно чинить вручную.
+func (mg *Migrator) Version() (version uint, dirty bool, err error) {
+	version, dirty, err = mg.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// Status перечисляет встроенные миграции по возрастанию версии.
+func (mg *Migrator) Status() (list []Migration, dirty bool, err error) {
+	version, dirty, err := mg.Version()
+	if err != nil {
+		return nil, false, err
+	}
+
+	list, err = embeddedMigrations(mg.fsys)
+	if err != nil {
+		return nil, false, err
+	}
+	for i := range list {
+		list[i].Applied = list[i].Version <= version
+	}
+	return list, dirty, nil
+}
+
+// Check проверяет, что схема не старше встроенных миграций и не испорчена.
+// Схема новее бинарника допустима: так бывает при поэтапном выкатывании.
+func (mg *Migrator) Check() error {
+	version, dirty, err := mg.Version()
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%w: migration %d is dirty", ErrSchemaOutdated, version)
+	}
+
+	list, err := embeddedMigrations(mg.fsys)
+	if err != nil {
+		return err
+	}
+	if latest := list[len(list)-1].Version; version < latest {
+		return fmt.Errorf("%w: version %d, expected %d, run `shortener migrate up`", ErrSchemaOutdated, version, latest)
+	}
+	return nil
+}
+
+// embeddedMigrations читает up-миграции из fsys.
+func embeddedMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Migration
+	for _, e := range entries {
+		m, err := source.DefaultParse(e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", e.Name(), err)
+		}
+		if m.Direction == source.Up {
+			list = append(list, Migration{Version: m.Version, Name: m.Identifier})
+		}
+	}
+	if len(list) == 0 {
+		return nil, errors.New("no embedded migrations")
+	}
+
+	slices.SortFunc(list, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return list, nil
+}
+
+// prepareSchema при старте либо применяет миграции, либо, если автоматические
+// миграции выключены, только проверяет версию схемы.
+func prepareSchema(dsn string, opts DBOptions) error {
+	mg, err := NewMigrator(dsn, opts.MigrationLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer mg.Close()
+
+	if opts.AutoMigrate {
+		return mg.Up()
+	}
+	return mg.Check()
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/Oleg2210/goshortener/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	list, err := embeddedMigrations(migrations.FS)
+	require.NoError(t, err)
+
+	for i, m := range list {
+		assert.Equal(t, uint(i+1), m.Version, "versions must go without gaps")
+
+		down := fmt.Sprintf("%06d_%s.down.sql", m.Version, m.Name)
+		_, err := fs.Stat(migrations.FS, down)
+		assert.NoError(t, err, "every migration needs a down step")
+	}
+}
+
+func TestSQLiteMigrationsMirrorPostgres(t *testing.T) {
+	postgres, err := embeddedMigrations(migrations.FS)
+	require.NoError(t, err)
+
+	sqlite, err := fs.Sub(migrations.SQLite, "sqlite")
+	require.NoError(t, err)
+	list, err := embeddedMigrations(sqlite)
+	require.NoError(t, err)
+	assert.Equal(t, postgres, list, "every Postgres migration needs a SQLite counterpart")
+
+	for _, m := range list {
+		_, err := fs.Stat(sqlite, fmt.Sprintf("%06d_%s.down.sql", m.Version, m.Name))
+		assert.NoError(t, err)
+	}
+}
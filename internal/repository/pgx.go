@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgxRepository — хранилище в Postgres поверх нативного пула pgx. В отличие от
+// DBRepository запросы идут именованными prepared statements в бинарном формате,
+// а многошаговые записи отправляются одним pgx.Batch за один обмен с базой.
+type PgxRepository struct {
+	Pool *pgxpool.Pool
+	DSN  string
+
+	guard
+}
+
+// NewPgxRepository дожидается базы и применяет миграции так же, как
+// NewDBRepository, после чего открывает пул. Запросы из statements готовятся
+// на каждом новом соединении, поэтому пул создается только после миграций.
+func NewPgxRepository(ctx context.Context, DSN string, opts DBOptions) (*PgxRepository, error) {
+	config, err := pgxpool.ParseConfig(DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.MaxOpenConns > 0 {
+		config.MaxConns = int32(opts.MaxOpenConns)
+	}
+	// простаивающие соединения pgxpool не ограничивает, а держит не меньше MinConns:
+	// MaxIdleConns задает, сколько соединений остается открытыми без нагрузки
+	config.MinConns = int32(min(opts.MaxIdleConns, int(config.MaxConns)))
+	config.MaxConnLifetime = opts.ConnMaxLifetime
+	config.MaxConnIdleTime = opts.ConnMaxIdleTime
+	config.AfterConnect = prepareStatements
+
+	repo := PgxRepository{
+		DSN:   DSN,
+		guard: newGuard(opts),
+	}
+
+	ping := func(ctx context.Context) error {
+		conn, err := pgx.ConnectConfig(ctx, config.ConnConfig.Copy())
+		if err != nil {
+			return err
+		}
+		return conn.Close(ctx)
+	}
+	if err := repo.connect(ctx, DSN, ping); err != nil {
+		return nil, err
+	}
+
+	repo.Pool, err = pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+func (repo *PgxRepository) Close() error {
+	repo.Pool.Close()
+	return nil
+}
+
+func prepareStatements(ctx context.Context, conn *pgx.Conn) error {
+	for _, s := range statements {
+		if _, err := conn.Prepare(ctx, s.name, s.sql); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repo *PgxRepository) Ping(ctx context.Context) bool {
+	return repo.run(ctx, repo.Pool.Ping) == nil
+}
+
+// queueVariants добавляет в batch вставку вариантов ссылки short.
+func queueVariants(batch *pgx.Batch, short string, variants []entities.Variant) {
+	for i, v := range variants {
+		batch.Queue(stmtInsertVariant.name, short, i, v.Name, v.URL, v.Weight, v.Clicks)
+	}
+}
+
+func (repo *PgxRepository) Save(ctx context.Context, record entities.URLRecord) (string, error) {
+	args, err := recordArgs(record)
+	if err != nil {
+		return "", err
+	}
+
+	var short string
+	err = repo.run(ctx, func(ctx context.Context) error {
+		if len(record.Variants) == 0 {
+			return repo.Pool.QueryRow(ctx, stmtSaveURL.name, args...).Scan(&short)
+		}
+
+		return pgx.BeginFunc(ctx, repo.Pool, func(tx pgx.Tx) error {
+			if err := tx.QueryRow(ctx, stmtSaveURL.name, args...).Scan(&short); err != nil {
+				return err
+			}
+			// при совпадении по dedupe варианты остаются у существующей ссылки
+			if short != record.Short {
+				return nil
+			}
+
+			var batch pgx.Batch
+			queueVariants(&batch, short, record.Variants)
+			return tx.SendBatch(ctx, &batch).Close()
+		})
+	})
+	if err != nil {
+		return "", translateError(err)
+	}
+	return short, nil
+}
+
+func (repo *PgxRepository) Get(ctx context.Context, id string) (string, bool) {
+	var fullURL string
+
+	err := repo.run(ctx, func(ctx context.Context) error {
+		return repo.Pool.QueryRow(ctx, stmtGetOriginal.name, id).Scan(&fullURL)
+	})
+	if err != nil {
+		return "", false
+	}
+
+	return fullURL, true
+}
+
+func (repo *PgxRepository) GetRecord(ctx context.Context, id string) (entities.URLRecord, error) {
+	var (
+		record        entities.URLRecord
+		allowlistJSON []byte
+		paramsJSON    []byte
+		rulesJSON     []byte
+		variantsJSON  []byte
+	)
+
+	err := repo.run(ctx, func(ctx context.Context) error {
+		return repo.Pool.QueryRow(ctx, stmtGetRecord.name, id).Scan(
+			&record.Short,
+			&record.OriginalURL,
+			&record.CreatedAt,
+			&record.Scope,
+			&record.Dedupe,
+			&record.Redirect.Code,
+			&record.Redirect.CacheMaxAge,
+			&record.Redirect.ReferrerPolicy,
+			&record.Redirect.QueryMode,
+			&allowlistJSON,
+			&paramsJSON,
+			&record.Redirect.Prefix,
+			&rulesJSON,
+			&variantsJSON,
+		)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entities.URLRecord{}, ErrNotFound
+	}
+	if err != nil {
+		return entities.URLRecord{}, err
+	}
+
+	if err := decodeRecord(&record, allowlistJSON, paramsJSON, rulesJSON, variantsJSON); err != nil {
+		return entities.URLRecord{}, err
+	}
+	return record, nil
+}
+
+// exec выполняет изменяющий запрос и возвращает ErrNotFound, если он не затронул ни одной строки.
+func (repo *PgxRepository) exec(ctx context.Context, s statement, args ...any) error {
+	return repo.run(ctx, func(ctx context.Context) error {
+		tag, err := repo.Pool.Exec(ctx, s.name, args...)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (repo *PgxRepository) SetRules(ctx context.Context, id string, rules []entities.Rule) error {
+	if rules == nil {
+		rules = []entities.Rule{}
+	}
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+
+	return repo.exec(ctx, stmtSetRules, id, string(rulesJSON))
+}
+
+func (repo *PgxRepository) SetVariants(ctx context.Context, id string, variants []entities.Variant) error {
+	return repo.run(ctx, func(ctx context.Context) error {
+		return pgx.BeginFunc(ctx, repo.Pool, func(tx pgx.Tx) error {
+			var exists bool
+			err := tx.QueryRow(ctx, stmtLockURL.name, id).Scan(&exists)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			if err != nil {
+				return err
+			}
+
+			rows, _ := tx.Query(ctx, stmtVariantClicks.name, id)
+			old, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.Variant, error) {
+				var v entities.Variant
+				err := row.Scan(&v.Name, &v.Clicks)
+				return v, err
+			})
+			if err != nil {
+				return err
+			}
+
+			var batch pgx.Batch
+			batch.Queue(stmtDeleteVariants.name, id)
+			queueVariants(&batch, id, carryClicks(old, variants))
+			return tx.SendBatch(ctx, &batch).Close()
+		})
+	})
+}
+
+func (repo *PgxRepository) IncrementClicks(ctx context.Context, id string, variant string) error {
+	return repo.exec(ctx, stmtIncrementClicks, id, variant)
+}
+
+// BatchSave в одной транзакции отправляет все вставки одним pgx.Batch, а
+// варианты вставленных записей — вторым, так что при конфликте не сохраняется
+// ни одна запись. Как и у DBRepository, запись, чей short или адрес уже заняты,
+// получает short существующей (он ищется отдельным запросом уже после пачки).
+func (repo *PgxRepository) BatchSave(ctx context.Context, records []entities.URLRecord) ([]string, error) {
+	var inserts pgx.Batch
+	for _, r := range records {
+		args, err := recordArgs(r)
+		if err != nil {
+			return nil, err
+		}
+		inserts.Queue(stmtInsertURLIfAbsent.name, args...)
+	}
+
+	shorts := make([]string, len(records))
+	err := repo.run(ctx, func(ctx context.Context) error {
+		return pgx.BeginFunc(ctx, repo.Pool, func(tx pgx.Tx) error {
+			var (
+				variants pgx.Batch
+				existing []int
+			)
+
+			results := tx.SendBatch(ctx, &inserts)
+			for i, r := range records {
+				err := results.QueryRow().Scan(&shorts[i])
+				if errors.Is(err, pgx.ErrNoRows) {
+					existing = append(existing, i)
+					continue
+				}
+				if err != nil {
+					results.Close()
+					return err
+				}
+				queueVariants(&variants, r.Short, r.Variants)
+			}
+			if err := results.Close(); err != nil {
+				return err
+			}
+
+			for _, i := range existing {
+				r := records[i]
+				err := tx.QueryRow(ctx, stmtFindExisting.name, r.Short, r.OriginalURL, r.Scope, r.Dedupe).Scan(&shorts[i])
+				if errors.Is(err, pgx.ErrNoRows) {
+					return ErrAlreadyExists
+				}
+				if err != nil {
+					return err
+				}
+			}
+
+			if variants.Len() == 0 {
+				return nil
+			}
+			return tx.SendBatch(ctx, &variants).Close()
+		})
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return shorts, nil
+}
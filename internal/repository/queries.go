@@ -0,0 +1,147 @@
+package repository
+
+// колонки urls с параметрами редиректа, в том же порядке, что и в recordArgs
+const redirectColumns = "redirect_code, cache_max_age, referrer_policy, query_mode, query_allowlist, default_params, prefix"
+
+// statement — запрос к Postgres. DBRepository выполняет sql как есть,
+// PgxRepository готовит его под именем name на каждом соединении пула.
+type statement struct {
+	name string
+	sql  string
+}
+
+var (
+	stmtInsertURL = statement{
+		"insert_url",
+		`INSERT INTO urls(short, original, scope, dedupe, ` + redirectColumns + `, rules) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+	}
+	// запись без dedupe не попадает в частичный индекс и конфликтовать по нему не может
+	stmtSaveURL = statement{
+		"save_url",
+		stmtInsertURL.sql + `
+		ON CONFLICT(scope, original) WHERE dedupe DO UPDATE
+		SET original = excluded.original RETURNING short`,
+	}
+	// при занятом short или адресе строк не возвращает: чья это запись, показывает stmtFindExisting
+	stmtInsertURLIfAbsent = statement{
+		"insert_url_if_absent",
+		stmtInsertURL.sql + ` ON CONFLICT DO NOTHING RETURNING short`,
+	}
+	// запись с тем же адресом в области ($4 — dedupe), а если ее нет, запись с тем же
+	// short и адресом: пачку повторяют после обрыва
+	stmtFindExisting = statement{
+		"find_existing",
+		`SELECT short FROM urls
+		WHERE original = $2 AND (short = $1 OR ($4 AND dedupe AND scope = $3))
+		ORDER BY short = $1
+		LIMIT 1`,
+	}
+	stmtInsertVariant = statement{
+		"insert_variant",
+		`INSERT INTO url_variants(short, position, name, url, weight, clicks) VALUES ($1, $2, $3, $4, $5, $6)`,
+	}
+	stmtGetOriginal = statement{
+		"get_original",
+		`SELECT original FROM urls WHERE short=$1`,
+	}
+	stmtGetRecord = statement{
+		"get_record",
+		`SELECT short, original, created_at, scope, dedupe, ` + redirectColumns + `, rules,
+			COALESCE((
+				SELECT json_agg(json_build_object('name', name, 'url', url, 'weight', weight, 'clicks', clicks) ORDER BY position)
+				FROM url_variants v WHERE v.short = urls.short
+			), '[]')
+		FROM urls WHERE short=$1`,
+	}
+	stmtSetRules = statement{
+		"set_rules",
+		`UPDATE urls SET rules = $2 WHERE short = $1`,
+	}
+	stmtLockURL = statement{
+		"lock_url",
+		`SELECT true FROM urls WHERE short = $1 FOR UPDATE`,
+	}
+	stmtVariantClicks = statement{
+		"variant_clicks",
+		`SELECT name, clicks FROM url_variants WHERE short = $1`,
+	}
+	stmtDeleteVariants = statement{
+		"delete_variants",
+		`DELETE FROM url_variants WHERE short = $1`,
+	}
+	stmtIncrementClicks = statement{
+		"increment_clicks",
+		`UPDATE url_variants SET clicks = clicks + 1 WHERE short = $1 AND name = $2`,
+	}
+)
+
+// запросы шардированного хранилища: глобальный индекс url_originals и перенос записей между шардами
+var (
+	// при конфликте возвращает short, уже записанный в индекс
+	stmtIndexOriginal = statement{
+		"index_original",
+		`INSERT INTO url_originals(scope, original, short) VALUES ($1, $2, $3)
+		ON CONFLICT(scope, original) DO UPDATE SET scope = excluded.scope RETURNING short`,
+	}
+	// при конфликте строк не возвращает: чей адрес, показывает stmtIndexedShort
+	stmtInsertOriginal = statement{
+		"insert_original",
+		`INSERT INTO url_originals(scope, original, short) VALUES ($1, $2, $3)
+		ON CONFLICT(scope, original) DO NOTHING RETURNING short`,
+	}
+	stmtIndexedShort = statement{
+		"indexed_short",
+		`SELECT short FROM url_originals WHERE scope = $1 AND original = $2`,
+	}
+	stmtRepointOriginal = statement{
+		"repoint_original",
+		`UPDATE url_originals SET short = $4 WHERE scope = $1 AND original = $2 AND short = $3`,
+	}
+	stmtUnindexOriginal = statement{
+		"unindex_original",
+		`DELETE FROM url_originals WHERE scope = $1 AND original = $2 AND short = $3`,
+	}
+	stmtReindexOriginals = statement{
+		"reindex_originals",
+		`INSERT INTO url_originals(scope, original, short)
+		SELECT * FROM unnest($1::text[], $2::text[], $3::text[])
+		ON CONFLICT DO NOTHING`,
+	}
+	stmtListURLs = statement{
+		"list_urls",
+		`SELECT short, scope, original, dedupe FROM urls WHERE short > $1 ORDER BY short LIMIT $2`,
+	}
+	stmtCountURLs = statement{
+		"count_urls",
+		`SELECT count(*) FROM urls`,
+	}
+	// запись переносится вместе с датой создания; повтор после сбоя ничего не меняет
+	stmtCopyURL = statement{
+		"copy_url",
+		`INSERT INTO urls(short, original, scope, dedupe, ` + redirectColumns + `, rules, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT(short) DO NOTHING`,
+	}
+	stmtDeleteURL = statement{
+		"delete_url",
+		`DELETE FROM urls WHERE short = $1`,
+	}
+	stmtDeleteURLs = statement{
+		"delete_urls",
+		`DELETE FROM urls WHERE short = ANY($1)`,
+	}
+)
+
+var statements = []statement{
+	stmtInsertURLIfAbsent,
+	stmtFindExisting,
+	stmtSaveURL,
+	stmtInsertVariant,
+	stmtGetOriginal,
+	stmtGetRecord,
+	stmtSetRules,
+	stmtLockURL,
+	stmtVariantClicks,
+	stmtDeleteVariants,
+	stmtIncrementClicks,
+}
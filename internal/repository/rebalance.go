@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+)
+
+// ShardStats — число записей на шарде и доля кольца, которая ему принадлежит.
+type ShardStats struct {
+	Shard   int
+	Records int64
+	Share   float64
+}
+
+// Stats считает записи на каждом шарде.
+func (repo *ShardedRepository) Stats(ctx context.Context) ([]ShardStats, error) {
+	shares := repo.ring.shares(len(repo.Shards))
+
+	result := make([]ShardStats, len(repo.Shards))
+	for i, shard := range repo.Shards {
+		result[i] = ShardStats{Shard: i, Share: shares[i]}
+		if err := shard.DB.QueryRowContext(ctx, stmtCountURLs.sql).Scan(&result[i].Records); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// RebalanceStats — итог перебалансировки.
+type RebalanceStats struct {
+	Scanned int
+	Moved   int
+}
+
+// Rebalance обходит все шарды страницами по batchSize записей, переносит
+// записи, которые по текущему кольцу принадлежат другому шарду, и дописывает
+// в глобальный индекс адреса, которых в нем нет (например, сохраненные до
+// включения шардирования). Перебалансировку можно прервать и запустить заново:
+// уже перенесенные записи не трогаются. progress, если задан, вызывается
+// после каждой страницы.
+func (repo *ShardedRepository) Rebalance(ctx context.Context, batchSize int, progress func(RebalanceStats)) (RebalanceStats, error) {
+	var stats RebalanceStats
+
+	for from, shard := range repo.Shards {
+		after := ""
+		for {
+			page, err := listURLs(ctx, shard.DB, after, batchSize)
+			if err != nil {
+				return stats, err
+			}
+			if len(page) == 0 {
+				break
+			}
+			after = page[len(page)-1].short
+
+			if err := repo.reindex(ctx, page); err != nil {
+				return stats, err
+			}
+			for _, u := range page {
+				stats.Scanned++
+				if to := repo.ring.locate(u.short); to != from {
+					if err := move(ctx, shard, repo.Shards[to], u.short); err != nil {
+						return stats, err
+					}
+					stats.Moved++
+				}
+			}
+
+			if progress != nil {
+				progress(stats)
+			}
+		}
+	}
+	return stats, nil
+}
+
+// listedURL — поля записи, нужные перебалансировке.
+type listedURL struct {
+	short    string
+	scope    string
+	original string
+	dedupe   bool
+}
+
+func listURLs(ctx context.Context, db *sql.DB, after string, limit int) ([]listedURL, error) {
+	rows, err := db.QueryContext(ctx, stmtListURLs.sql, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var page []listedURL
+	for rows.Next() {
+		var u listedURL
+		if err := rows.Scan(&u.short, &u.scope, &u.original, &u.dedupe); err != nil {
+			return nil, err
+		}
+		page = append(page, u)
+	}
+	return page, rows.Err()
+}
+
+// reindex дописывает в глобальный индекс адреса записей страницы с dedupe.
+func (repo *ShardedRepository) reindex(ctx context.Context, page []listedURL) error {
+	var scopes, originals, shorts []string
+	for _, u := range page {
+		if u.dedupe {
+			scopes = append(scopes, u.scope)
+			originals = append(originals, u.original)
+			shorts = append(shorts, u.short)
+		}
+	}
+	if len(shorts) == 0 {
+		return nil
+	}
+
+	_, err := repo.Index.DB.ExecContext(ctx, stmtReindexOriginals.sql, scopes, originals, shorts)
+	return err
+}
+
+// move переносит запись short с шарда from на шард to вместе с правилами,
+// вариантами и их счетчиками. Запись на from заблокирована до удаления, поэтому
+// изменения, пришедшие во время переноса, дождутся его и повторятся на to.
+func move(ctx context.Context, from, to *DBRepository, short string) error {
+	tx, err := from.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	err = tx.QueryRowContext(ctx, stmtLockURL.sql, short).Scan(&locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		// запись удалили или перенесли, пока шел обход
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	record, err := getRecord(ctx, tx, stmtGetRecord.sql, short)
+	if err != nil {
+		return err
+	}
+	if err := copyRecord(ctx, to.DB, record); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, stmtDeleteURL.sql, short); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// copyRecord вставляет запись как есть. Если запись уже есть (прошлый запуск
+// прервался после копирования), ничего не меняется.
+func copyRecord(ctx context.Context, db *sql.DB, record entities.URLRecord) error {
+	args, err := recordArgs(record)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, stmtCopyURL.sql, append(args, record.CreatedAt)...)
+	if err != nil {
+		return translateError(err)
+	}
+	if copied, err := result.RowsAffected(); err != nil || copied == 0 {
+		return err
+	}
+
+	if err := insertVariants(ctx, tx, record.Short, record.Variants); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Oleg2210/goshortener/internal/metrics"
+)
+
+// replica — реплика только для чтения и ее состояние по последней проверке.
+type replica struct {
+	db *sql.DB
+	// номер реплики в метриках, DSN с паролем в метки не попадает
+	name    string
+	healthy atomic.Bool
+}
+
+func (r *replica) setHealthy(healthy bool) {
+	r.healthy.Store(healthy)
+
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	metrics.DBReplicaHealthy.WithLabelValues(r.name).Set(value)
+}
+
+// replicaSet раздает чтения по исправным репликам по кругу. Id, записанные
+// этим процессом не позже window назад, читаются с primary: реплика могла
+// еще не получить запись.
+type replicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+
+	timeout time.Duration
+	window  time.Duration
+	now     func() time.Time
+
+	mu     sync.Mutex
+	recent map[string]time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newReplicaSet открывает пулы реплик и один раз проверяет каждую. Недоступная
+// реплика не мешает старту: она включится, когда пройдет проверку.
+func newReplicaSet(ctx context.Context, dsns []string, opts DBOptions) (*replicaSet, error) {
+	set := &replicaSet{
+		timeout: opts.QueryTimeout,
+		window:  opts.ReadYourWritesWindow,
+		now:     time.Now,
+		recent:  make(map[string]time.Time),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	for i, dsn := range dsns {
+		db, err := sql.Open("pgx", dsn)
+		if err != nil {
+			set.closeDBs()
+			return nil, err
+		}
+
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+		db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+
+		set.replicas = append(set.replicas, &replica{db: db, name: strconv.Itoa(i)})
+	}
+
+	set.check(ctx)
+	go set.loop(opts.ReplicaCheckInterval)
+	return set, nil
+}
+
+// pick возвращает следующую исправную реплику для чтения id или nil, если
+// читать нужно с primary.
+func (s *replicaSet) pick(id string) *replica {
+	if s == nil || s.fresh(id) {
+		return nil
+	}
+
+	n := uint64(len(s.replicas))
+	start := s.next.Add(1)
+	for i := range n {
+		if r := s.replicas[(start+i)%n]; r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// written отмечает id как только что записанные.
+func (s *replicaSet) written(ids ...string) {
+	if s == nil || s.window <= 0 {
+		return
+	}
+
+	expires := s.now().Add(s.window)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		s.recent[id] = expires
+	}
+}
+
+func (s *replicaSet) fresh(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expires, ok := s.recent[id]
+	return ok && s.now().Before(expires)
+}
+
+// sweep забывает записи, окно которых истекло.
+func (s *replicaSet) sweep() {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, expires := range s.recent {
+		if !now.Before(expires) {
+			delete(s.recent, id)
+		}
+	}
+}
+
+func (s *replicaSet) check(ctx context.Context) {
+	for _, r := range s.replicas {
+		r.setHealthy(s.ping(ctx, r) == nil)
+	}
+}
+
+func (s *replicaSet) ping(ctx context.Context, r *replica) error {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	return r.db.PingContext(ctx)
+}
+
+func (s *replicaSet) loop(interval time.Duration) {
+	defer close(s.done)
+
+	if interval <= 0 {
+		interval = DefaultDBOptions().ReplicaCheckInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.check(context.Background())
+			s.sweep()
+		}
+	}
+}
+
+func (s *replicaSet) closeDBs() {
+	for _, r := range s.replicas {
+		r.db.Close()
+	}
+}
+
+func (s *replicaSet) close() {
+	if s == nil {
+		return
+	}
+
+	close(s.stop)
+	<-s.done
+	s.closeDBs()
+}
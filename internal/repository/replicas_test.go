@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestReplicaSet собирает набор из n реплик без проверки здоровья и
+// фонового цикла. Пулы открываются лениво и к базе не подключаются.
+func newTestReplicaSet(t *testing.T, n int, window time.Duration) *replicaSet {
+	set := &replicaSet{window: window, now: time.Now, recent: make(map[string]time.Time)}
+	for i := range n {
+		db, err := sql.Open("pgx", "postgres://localhost:1/replica")
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		r := &replica{db: db, name: strconv.Itoa(i)}
+		r.setHealthy(true)
+		set.replicas = append(set.replicas, r)
+	}
+	return set
+}
+
+func TestReplicaRoundRobin(t *testing.T) {
+	set := newTestReplicaSet(t, 3, 0)
+
+	seen := map[*replica]int{}
+	for range 6 {
+		seen[set.pick("id")]++
+	}
+	for _, r := range set.replicas {
+		assert.Equal(t, 2, seen[r])
+	}
+
+	set.replicas[0].setHealthy(false)
+	set.replicas[2].setHealthy(false)
+	for range 3 {
+		assert.Same(t, set.replicas[1], set.pick("id"))
+	}
+
+	set.replicas[1].setHealthy(false)
+	assert.Nil(t, set.pick("id"), "no healthy replica: read from primary")
+}
+
+func TestReplicaReadYourWrites(t *testing.T) {
+	now := time.Unix(0, 0)
+	set := newTestReplicaSet(t, 1, time.Second)
+	set.now = func() time.Time { return now }
+
+	set.written("new", "other")
+	assert.Nil(t, set.pick("new"))
+	assert.NotNil(t, set.pick("old"))
+
+	now = now.Add(time.Second)
+	assert.NotNil(t, set.pick("new"), "window is over")
+
+	set.sweep()
+	assert.Empty(t, set.recent)
+
+	var disabled *replicaSet
+	disabled.written("id")
+	assert.Nil(t, disabled.pick("id"))
+}
+
+func TestReadFallsBackToPrimary(t *testing.T) {
+	repo := newTestDBRepository(DBOptions{})
+	repo.DB, _ = sql.Open("pgx", "postgres://localhost:1/primary")
+	defer repo.DB.Close()
+	repo.replicas = newTestReplicaSet(t, 1, time.Minute)
+	replica := repo.replicas.replicas[0]
+
+	var used []*sql.DB
+	read := func(id string, replicaErr error) error {
+		used = nil
+		return repo.read(context.Background(), id, func(ctx context.Context, db *sql.DB) error {
+			used = append(used, db)
+			if db == replica.db {
+				return replicaErr
+			}
+			return nil
+		})
+	}
+
+	require.NoError(t, read("id", nil))
+	assert.Equal(t, []*sql.DB{replica.db}, used)
+
+	// реплика могла еще не получить запись: повтор на primary, реплика остается в строю
+	require.NoError(t, read("id", sql.ErrNoRows))
+	assert.Equal(t, []*sql.DB{replica.db, repo.DB}, used)
+	assert.True(t, replica.healthy.Load())
+
+	repo.replicas.written("fresh")
+	require.NoError(t, read("fresh", nil))
+	assert.Equal(t, []*sql.DB{repo.DB}, used)
+
+	// недоступная реплика выводится из ротации до следующей проверки
+	require.NoError(t, read("id", &net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+	assert.Equal(t, []*sql.DB{replica.db, repo.DB}, used)
+	assert.False(t, replica.healthy.Load())
+
+	require.NoError(t, read("id", nil))
+	assert.Equal(t, []*sql.DB{repo.DB}, used)
+}
+
+func TestFailedWriteKeepsReplicaReads(t *testing.T) {
+	repo := newTestDBRepository(DBOptions{})
+	repo.DB, _ = sql.Open("pgx", "postgres://localhost:1/primary")
+	defer repo.DB.Close()
+	repo.replicas = newTestReplicaSet(t, 1, time.Minute)
+
+	assert.Error(t, repo.SetRules(context.Background(), "id", nil))
+	assert.Error(t, repo.SetVariants(context.Background(), "id", nil))
+	assert.Empty(t, repo.replicas.recent, "nothing was written")
+}
@@ -9,9 +9,31 @@ import (
 
 var ErrAlreadyExists = errors.New("id already exists")
 
+var ErrOriginalExists = errors.New("original url already exists")
+
+var ErrNotFound = errors.New("record not found")
+
+// ErrUnavailable — хранилище временно недоступно, запрос можно повторить позже.
+var ErrUnavailable = errors.New("storage unavailable")
+
 type URLRepository interface {
-	Save(ctx context.Context, id string, url string) (string, error)
-	BatchSave(ctx context.Context, records []entities.URLRecord) error
+	// Save сохраняет запись и возвращает ее short. Если record.Dedupe и в области
+	// record.Scope уже есть запись с Dedupe и тем же OriginalURL, новая не создается,
+	// а возвращается short существующей. Занятый short — ErrAlreadyExists.
+	Save(ctx context.Context, record entities.URLRecord) (string, error)
+	// BatchSave сохраняет все записи или ни одной и возвращает short каждой. Запись
+	// с Dedupe, чей адрес уже есть в области (в хранилище или раньше в пачке), не
+	// создается: как и в Save, возвращается short существующей. Занятый short —
+	// ErrAlreadyExists; запись с тем же short и OriginalURL, сохраненная прежде,
+	// не конфликт, поэтому пачку можно повторить.
+	BatchSave(ctx context.Context, records []entities.URLRecord) ([]string, error)
 	Get(ctx context.Context, id string) (string, bool)
+	// GetRecord возвращает запись целиком; отсутствующий id — ErrNotFound
+	GetRecord(ctx context.Context, id string) (entities.URLRecord, error)
+	// SetRules заменяет правила ссылки целиком
+	SetRules(ctx context.Context, id string, rules []entities.Rule) error
+	// SetVariants заменяет варианты ссылки; у вариантов с прежними именами клики сохраняются
+	SetVariants(ctx context.Context, id string, variants []entities.Variant) error
+	IncrementClicks(ctx context.Context, id string, variant string) error
 	Ping(ctx context.Context) bool
 }
@@ -0,0 +1,105 @@
+package repositorytest
+
+import (
+	"testing"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunBatch проверяет, что BatchSave сохраняет пачку целиком или не сохраняет ничего.
+func RunBatch(t *testing.T, newRepo Factory) {
+	t.Run("saves all", func(t *testing.T) {
+		repo := newRepo(t)
+
+		shorts, err := repo.BatchSave(t.Context(), []entities.URLRecord{
+			link("b1", "https://example.com/1"),
+			link("b2", "https://example.com/2"),
+			{Short: "b3", OriginalURL: "https://example.com/3", Variants: []entities.Variant{
+				{Name: "a", URL: "https://example.com/3a", Weight: 1},
+			}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b1", "b2", "b3"}, shorts)
+
+		for short, original := range map[string]string{"b1": "https://example.com/1", "b2": "https://example.com/2"} {
+			got, exists := repo.Get(t.Context(), short)
+			assert.True(t, exists, short)
+			assert.Equal(t, original, got)
+		}
+
+		stored, err := repo.GetRecord(t.Context(), "b3")
+		require.NoError(t, err)
+		assert.Len(t, stored.Variants, 1)
+	})
+
+	t.Run("empty batch", func(t *testing.T) {
+		repo := newRepo(t)
+		shorts, err := repo.BatchSave(t.Context(), nil)
+		assert.NoError(t, err)
+		assert.Empty(t, shorts)
+	})
+
+	t.Run("taken id rolls back", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.Save(t.Context(), link("taken", "https://example.com/taken"))
+		require.NoError(t, err)
+
+		_, err = repo.BatchSave(t.Context(), []entities.URLRecord{
+			link("b1", "https://example.com/1"),
+			link("taken", "https://example.com/2"),
+		})
+		assert.ErrorIs(t, err, repository.ErrAlreadyExists)
+
+		_, exists := repo.Get(t.Context(), "b1")
+		assert.False(t, exists)
+
+		original, _ := repo.Get(t.Context(), "taken")
+		assert.Equal(t, "https://example.com/taken", original)
+	})
+
+	t.Run("repeated id in batch", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.BatchSave(t.Context(), []entities.URLRecord{
+			link("b1", "https://example.com/1"),
+			link("b1", "https://example.com/2"),
+		})
+		assert.ErrorIs(t, err, repository.ErrAlreadyExists)
+
+		_, exists := repo.Get(t.Context(), "b1")
+		assert.False(t, exists)
+	})
+
+	t.Run("repeated batch is saved once", func(t *testing.T) {
+		repo := newRepo(t)
+
+		batch := []entities.URLRecord{
+			link("b1", "https://example.com/1"),
+			{Short: "b2", OriginalURL: "https://example.com/2", Dedupe: true},
+			{Short: "b3", OriginalURL: "https://example.com/3", Variants: []entities.Variant{
+				{Name: "a", URL: "https://example.com/3a", Weight: 1},
+			}},
+		}
+		_, err := repo.BatchSave(t.Context(), batch)
+		require.NoError(t, err)
+		require.NoError(t, repo.IncrementClicks(t.Context(), "b3", "a"))
+
+		// повтор после обрыва: часть пачки уже сохранена
+		shorts, err := repo.BatchSave(t.Context(), append(batch, link("b4", "https://example.com/4")))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b1", "b2", "b3", "b4"}, shorts)
+
+		stored, err := repo.GetRecord(t.Context(), "b3")
+		require.NoError(t, err)
+		require.Len(t, stored.Variants, 1)
+		assert.Equal(t, int64(1), stored.Variants[0].Clicks, "variants are not rewritten")
+
+		original, exists := repo.Get(t.Context(), "b4")
+		assert.True(t, exists)
+		assert.Equal(t, "https://example.com/4", original)
+	})
+}
@@ -0,0 +1,52 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunCancellation проверяет, что отмененный контекст не меняет данные
+// и возвращает context.Canceled.
+func RunCancellation(t *testing.T, newRepo Factory) {
+	repo := newRepo(t)
+
+	_, err := repo.Save(t.Context(), entities.URLRecord{
+		Short:       "live",
+		OriginalURL: "https://example.com/live",
+		Variants:    []entities.Variant{{Name: "a", URL: "https://example.com/a", Weight: 1}},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err = repo.Save(ctx, link("abc", "https://example.com/a"))
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = repo.BatchSave(ctx, []entities.URLRecord{link("b1", "https://example.com/1")})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.ErrorIs(t, repo.SetRules(ctx, "live", nil), context.Canceled)
+	assert.ErrorIs(t, repo.SetVariants(ctx, "live", nil), context.Canceled)
+	assert.ErrorIs(t, repo.IncrementClicks(ctx, "live", "a"), context.Canceled)
+
+	_, exists := repo.Get(ctx, "live")
+	assert.False(t, exists)
+	_, err = repo.GetRecord(ctx, "live")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, repo.Ping(ctx))
+
+	for _, short := range []string{"abc", "b1"} {
+		_, exists := repo.Get(t.Context(), short)
+		assert.False(t, exists, short)
+	}
+
+	stored, err := repo.GetRecord(t.Context(), "live")
+	require.NoError(t, err)
+	assert.Len(t, stored.Variants, 1)
+	assert.Zero(t, stored.Variants[0].Clicks)
+}
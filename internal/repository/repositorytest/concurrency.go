@@ -0,0 +1,89 @@
+package repositorytest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const workers = 16
+
+// RunConcurrency проверяет хранилище под параллельной записью. Имеет смысл
+// запускать с -race.
+func RunConcurrency(t *testing.T, newRepo Factory) {
+	t.Run("distinct saves", func(t *testing.T) {
+		repo := newRepo(t)
+
+		var wg sync.WaitGroup
+		errs := make([]error, workers)
+		for i := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = repo.Save(t.Context(), link(fmt.Sprintf("id%d", i), fmt.Sprintf("https://example.com/%d", i)))
+			}()
+		}
+		wg.Wait()
+
+		for i := range workers {
+			require.NoError(t, errs[i])
+			original, exists := repo.Get(t.Context(), fmt.Sprintf("id%d", i))
+			assert.True(t, exists)
+			assert.Equal(t, fmt.Sprintf("https://example.com/%d", i), original)
+		}
+	})
+
+	t.Run("same original", func(t *testing.T) {
+		repo := newRepo(t)
+
+		var wg sync.WaitGroup
+		shorts := make([]string, workers)
+		errs := make([]error, workers)
+		for i := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				shorts[i], errs[i] = repo.Save(t.Context(), link(fmt.Sprintf("id%d", i), "https://example.com/same"))
+			}()
+		}
+		wg.Wait()
+
+		for i := range workers {
+			require.NoError(t, errs[i])
+			assert.Equal(t, shorts[0], shorts[i], "all writers must get the same short")
+		}
+	})
+
+	t.Run("clicks", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.Save(t.Context(), entities.URLRecord{
+			Short:       "ab",
+			OriginalURL: "https://example.com/ab",
+			Variants:    []entities.Variant{{Name: "a", URL: "https://example.com/a", Weight: 1}},
+		})
+		require.NoError(t, err)
+
+		const perWorker = 25
+		var wg sync.WaitGroup
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range perWorker {
+					assert.NoError(t, repo.IncrementClicks(t.Context(), "ab", "a"))
+					repo.GetRecord(t.Context(), "ab")
+				}
+			}()
+		}
+		wg.Wait()
+
+		stored, _ := repo.GetRecord(t.Context(), "ab")
+		require.Len(t, stored.Variants, 1)
+		assert.Equal(t, int64(workers*perWorker), stored.Variants[0].Clicks)
+	})
+}
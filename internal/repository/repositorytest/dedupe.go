@@ -0,0 +1,156 @@
+package repositorytest
+
+import (
+	"testing"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunDedupe проверяет индекс original→short: повторное сохранение адреса с Dedupe
+// возвращает прежний short в пределах области, записи без Dedupe в индекс не попадают.
+func RunDedupe(t *testing.T, newRepo Factory) {
+	record := func(short, original, scope string, dedupe bool) entities.URLRecord {
+		return entities.URLRecord{Short: short, OriginalURL: original, Scope: scope, Dedupe: dedupe}
+	}
+
+	t.Run("same original returns first short", func(t *testing.T) {
+		repo := newRepo(t)
+
+		short, err := repo.Save(t.Context(), record("first", "https://example.com/a", "", true))
+		require.NoError(t, err)
+		assert.Equal(t, "first", short)
+
+		short, err = repo.Save(t.Context(), record("second", "https://example.com/a", "", true))
+		require.NoError(t, err)
+		assert.Equal(t, "first", short)
+
+		_, exists := repo.Get(t.Context(), "second")
+		assert.False(t, exists)
+	})
+
+	t.Run("dedupe off creates new short and keeps index", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.Save(t.Context(), record("first", "https://example.com/a", "", true))
+		require.NoError(t, err)
+
+		short, err := repo.Save(t.Context(), record("copy", "https://example.com/a", "", false))
+		require.NoError(t, err)
+		assert.Equal(t, "copy", short)
+
+		short, err = repo.Save(t.Context(), record("third", "https://example.com/a", "", true))
+		require.NoError(t, err)
+		assert.Equal(t, "first", short)
+	})
+
+	t.Run("records without dedupe are not indexed", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.Save(t.Context(), record("copy", "https://example.com/a", "", false))
+		require.NoError(t, err)
+
+		short, err := repo.Save(t.Context(), record("first", "https://example.com/a", "", true))
+		require.NoError(t, err)
+		assert.Equal(t, "first", short)
+
+		stored, err := repo.GetRecord(t.Context(), "first")
+		require.NoError(t, err)
+		assert.True(t, stored.Dedupe)
+	})
+
+	t.Run("scopes are independent", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for _, scope := range []string{"", "alice", "bob"} {
+			short, err := repo.Save(t.Context(), record("id-"+scope, "https://example.com/a", scope, true))
+			require.NoError(t, err)
+			assert.Equal(t, "id-"+scope, short)
+		}
+
+		short, err := repo.Save(t.Context(), record("again", "https://example.com/a", "alice", true))
+		require.NoError(t, err)
+		assert.Equal(t, "id-alice", short)
+
+		stored, err := repo.GetRecord(t.Context(), "id-bob")
+		require.NoError(t, err)
+		assert.Equal(t, "bob", stored.Scope)
+	})
+
+	t.Run("taken short is a conflict", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.Save(t.Context(), record("first", "https://example.com/a", "", true))
+		require.NoError(t, err)
+
+		_, err = repo.Save(t.Context(), record("first", "https://example.com/b", "", true))
+		assert.ErrorIs(t, err, repository.ErrAlreadyExists)
+	})
+
+	t.Run("batch with indexed original returns first short", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.Save(t.Context(), record("first", "https://example.com/a", "", true))
+		require.NoError(t, err)
+
+		shorts, err := repo.BatchSave(t.Context(), []entities.URLRecord{
+			record("b1", "https://example.com/b", "", true),
+			record("b2", "https://example.com/a", "", true),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b1", "first"}, shorts)
+
+		_, exists := repo.Get(t.Context(), "b2")
+		assert.False(t, exists)
+	})
+
+	t.Run("batch with repeated original returns first short", func(t *testing.T) {
+		repo := newRepo(t)
+
+		shorts, err := repo.BatchSave(t.Context(), []entities.URLRecord{
+			record("b1", "https://example.com/b", "", true),
+			record("b2", "https://example.com/b", "", true),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b1", "b1"}, shorts)
+
+		_, exists := repo.Get(t.Context(), "b2")
+		assert.False(t, exists)
+	})
+
+	t.Run("batch resolves original before taken short", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.Save(t.Context(), record("first", "https://example.com/a", "", true))
+		require.NoError(t, err)
+		_, err = repo.Save(t.Context(), record("taken", "https://example.com/other", "", false))
+		require.NoError(t, err)
+
+		shorts, err := repo.BatchSave(t.Context(), []entities.URLRecord{
+			record("taken", "https://example.com/a", "", true),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"first"}, shorts)
+	})
+
+	t.Run("batch without dedupe allows repeats", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.Save(t.Context(), record("first", "https://example.com/a", "", true))
+		require.NoError(t, err)
+
+		shorts, err := repo.BatchSave(t.Context(), []entities.URLRecord{
+			record("b1", "https://example.com/a", "", false),
+			record("b2", "https://example.com/a", "", false),
+			record("b3", "https://example.com/a", "other", true),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b1", "b2", "b3"}, shorts)
+
+		short, err := repo.Save(t.Context(), record("again", "https://example.com/a", "other", true))
+		require.NoError(t, err)
+		assert.Equal(t, "b3", short)
+	})
+}
@@ -0,0 +1,190 @@
+package repositorytest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunSave проверяет сохранение и чтение одиночных ссылок.
+func RunSave(t *testing.T, newRepo Factory) {
+	t.Run("save then get", func(t *testing.T) {
+		repo := newRepo(t)
+
+		short, err := repo.Save(t.Context(), link("abc", "https://example.com/a"))
+		require.NoError(t, err)
+		assert.Equal(t, "abc", short)
+
+		original, exists := repo.Get(t.Context(), "abc")
+		assert.True(t, exists)
+		assert.Equal(t, "https://example.com/a", original)
+	})
+
+	t.Run("missing id", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, exists := repo.Get(t.Context(), "missing")
+		assert.False(t, exists)
+
+		_, err := repo.GetRecord(t.Context(), "missing")
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("ids are case sensitive", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.Save(t.Context(), link("abc", "https://example.com/lower"))
+		require.NoError(t, err)
+		_, err = repo.Save(t.Context(), link("ABC", "https://example.com/upper"))
+		require.NoError(t, err)
+
+		original, _ := repo.Get(t.Context(), "ABC")
+		assert.Equal(t, "https://example.com/upper", original)
+	})
+
+	t.Run("taken id is not overwritten", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.Save(t.Context(), link("abc", "https://example.com/a"))
+		require.NoError(t, err)
+
+		_, err = repo.Save(t.Context(), link("abc", "https://example.com/b"))
+		assert.ErrorIs(t, err, repository.ErrAlreadyExists)
+
+		original, _ := repo.Get(t.Context(), "abc")
+		assert.Equal(t, "https://example.com/a", original)
+	})
+
+	t.Run("created at is set", func(t *testing.T) {
+		repo := newRepo(t)
+
+		before := time.Now()
+		_, err := repo.Save(t.Context(), link("abc", "https://example.com/a"))
+		require.NoError(t, err)
+
+		record, err := repo.GetRecord(t.Context(), "abc")
+		require.NoError(t, err)
+		assert.WithinDuration(t, before, record.CreatedAt, time.Minute)
+	})
+
+	t.Run("ping", func(t *testing.T) {
+		repo := newRepo(t)
+		assert.True(t, repo.Ping(t.Context()))
+	})
+}
+
+// RunRecord проверяет, что все поля записи переживают сохранение, а правила,
+// варианты и счетчики меняются только у существующих ссылок.
+func RunRecord(t *testing.T, newRepo Factory) {
+	record := entities.URLRecord{
+		Short:       "full",
+		OriginalURL: "https://example.com/full",
+		Redirect: entities.RedirectPolicy{
+			Code:           http.StatusMovedPermanently,
+			CacheMaxAge:    entities.MaxAge(60),
+			ReferrerPolicy: "no-referrer",
+			QueryMode:      entities.QueryAllowlist,
+			QueryAllowlist: []string{"utm_source", "utm_campaign"},
+			DefaultParams:  map[string]string{"ref": "short"},
+			Prefix:         true,
+		},
+		Rules: []entities.Rule{{
+			Conditions: entities.RuleConditions{Devices: []string{"ios"}, Countries: []string{"DE"}},
+			Target:     "https://example.com/ios",
+		}},
+		Variants: []entities.Variant{
+			{Name: "a", URL: "https://example.com/a", Weight: 3},
+			{Name: "b", URL: "https://example.com/b", Weight: 1},
+		},
+		Scope:  "alice",
+		Dedupe: true,
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.Save(t.Context(), record)
+		require.NoError(t, err)
+
+		stored, err := repo.GetRecord(t.Context(), "full")
+		require.NoError(t, err)
+		assert.Equal(t, record.OriginalURL, stored.OriginalURL)
+		assert.Equal(t, record.Short, stored.Short)
+		assert.Equal(t, record.Redirect, stored.Redirect)
+		assert.Equal(t, record.Rules, stored.Rules)
+		assert.Equal(t, record.Variants, stored.Variants)
+		assert.Equal(t, record.Scope, stored.Scope)
+		assert.True(t, stored.Dedupe)
+	})
+
+	t.Run("explicit zero cache lifetime is kept", func(t *testing.T) {
+		repo := newRepo(t)
+
+		uncached := link("uncached", "https://example.com/uncached")
+		uncached.Redirect.CacheMaxAge = entities.MaxAge(0)
+		_, err := repo.Save(t.Context(), uncached)
+		require.NoError(t, err)
+		_, err = repo.BatchSave(t.Context(), []entities.URLRecord{link("inherited", "https://example.com/inherited")})
+		require.NoError(t, err)
+
+		stored, err := repo.GetRecord(t.Context(), "uncached")
+		require.NoError(t, err)
+		assert.Equal(t, entities.MaxAge(0), stored.Redirect.CacheMaxAge)
+
+		stored, err = repo.GetRecord(t.Context(), "inherited")
+		require.NoError(t, err)
+		assert.Nil(t, stored.Redirect.CacheMaxAge, "unset lifetime must stay unset")
+	})
+
+	t.Run("rules", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.Save(t.Context(), link("abc", "https://example.com/a"))
+		require.NoError(t, err)
+
+		require.NoError(t, repo.SetRules(t.Context(), "abc", record.Rules))
+		stored, _ := repo.GetRecord(t.Context(), "abc")
+		assert.Equal(t, record.Rules, stored.Rules)
+
+		require.NoError(t, repo.SetRules(t.Context(), "abc", nil))
+		stored, _ = repo.GetRecord(t.Context(), "abc")
+		assert.Empty(t, stored.Rules)
+
+		assert.ErrorIs(t, repo.SetRules(t.Context(), "missing", record.Rules), repository.ErrNotFound)
+	})
+
+	t.Run("variants and clicks", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.Save(t.Context(), record)
+		require.NoError(t, err)
+
+		require.NoError(t, repo.IncrementClicks(t.Context(), "full", "a"))
+		require.NoError(t, repo.IncrementClicks(t.Context(), "full", "a"))
+		require.NoError(t, repo.IncrementClicks(t.Context(), "full", "b"))
+		assert.ErrorIs(t, repo.IncrementClicks(t.Context(), "full", "missing"), repository.ErrNotFound)
+		assert.ErrorIs(t, repo.IncrementClicks(t.Context(), "missing", "a"), repository.ErrNotFound)
+
+		// клики переносятся по имени варианта, новые начинаются с нуля
+		require.NoError(t, repo.SetVariants(t.Context(), "full", []entities.Variant{
+			{Name: "c", URL: "https://example.com/c", Weight: 1},
+			{Name: "a", URL: "https://example.com/a2", Weight: 5},
+		}))
+		stored, _ := repo.GetRecord(t.Context(), "full")
+		assert.Equal(t, []entities.Variant{
+			{Name: "c", URL: "https://example.com/c", Weight: 1},
+			{Name: "a", URL: "https://example.com/a2", Weight: 5, Clicks: 2},
+		}, stored.Variants)
+
+		require.NoError(t, repo.SetVariants(t.Context(), "full", nil))
+		stored, _ = repo.GetRecord(t.Context(), "full")
+		assert.Empty(t, stored.Variants)
+
+		assert.ErrorIs(t, repo.SetVariants(t.Context(), "missing", nil), repository.ErrNotFound)
+	})
+}
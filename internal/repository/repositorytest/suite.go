@@ -0,0 +1,30 @@
+// Package repositorytest содержит общие проверки для реализаций repository.URLRepository.
+// Каждое хранилище прогоняется через одни и те же сценарии, чтобы семантика
+// не расходилась между бэкендами. Новый бэкенд подключается одним вызовом Run
+// из его теста.
+package repositorytest
+
+import (
+	"testing"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/repository"
+)
+
+// Factory создает пустое хранилище для одного подтеста. Освобождение ресурсов
+// фабрика регистрирует через t.Cleanup.
+type Factory func(t *testing.T) repository.URLRepository
+
+// Run прогоняет все сценарии против хранилищ из newRepo.
+func Run(t *testing.T, newRepo Factory) {
+	t.Run("save", func(t *testing.T) { RunSave(t, newRepo) })
+	t.Run("record", func(t *testing.T) { RunRecord(t, newRepo) })
+	t.Run("batch", func(t *testing.T) { RunBatch(t, newRepo) })
+	t.Run("dedupe", func(t *testing.T) { RunDedupe(t, newRepo) })
+	t.Run("cancellation", func(t *testing.T) { RunCancellation(t, newRepo) })
+	t.Run("concurrency", func(t *testing.T) { RunConcurrency(t, newRepo) })
+}
+
+func link(short, original string) entities.URLRecord {
+	return entities.URLRecord{Short: short, OriginalURL: original, Dedupe: true}
+}
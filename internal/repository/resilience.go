@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/Oleg2210/goshortener/internal/metrics"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// пауза перед первым повтором запроса и верхняя граница любой паузы
+const (
+	retryBackoff = 50 * time.Millisecond
+	maxBackoff   = 30 * time.Second
+)
+
+// backoff возвращает паузу перед повтором номер attempt (с нуля): base·2^attempt.
+func backoff(base time.Duration, attempt int) time.Duration {
+	d := base << min(attempt, 16)
+	if d <= 0 || d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// коды Postgres, при которых запрос точно не был выполнен и его можно повторить
+var transientCodes = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"53300": true, // too_many_connections
+	"57P03": true, // cannot_connect_now
+	"08001": true, // sqlclient_unable_to_establish_sqlconnection
+	"08004": true, // sqlserver_rejected_establishment_of_sqlconnection
+}
+
+// isTransient сообщает, что запрос не дошел до базы или был ею отклонен
+// без последствий, поэтому повтор безопасен и для записи.
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && transientCodes[pgErr.Code]
+}
+
+// isOutage сообщает, что ошибка говорит о недоступности базы, а не об ответе
+// исправной базы (нет записи, нарушение уникальности и т.п.).
+func isOutage(err error) bool {
+	if err == nil {
+		return false
+	}
+	if isTransient(err) || errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err) {
+		return true
+	}
+
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	if errors.As(err, &connectErr) || errors.As(err, &netErr) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && len(pgErr.Code) == 5 {
+		switch pgErr.Code[:2] {
+		case "08", "53", "57", "58":
+			return true
+		}
+	}
+	return false
+}
+
+// guard — общая для Postgres-хранилищ обвязка запросов: таймаут, повторы
+// временных ошибок и выключатель цепи.
+type guard struct {
+	opts    DBOptions
+	breaker *breaker
+}
+
+func newGuard(opts DBOptions) guard {
+	return guard{opts: opts, breaker: newBreaker(opts.BreakerThreshold, opts.BreakerCooldown)}
+}
+
+// connect дожидается базы и готовит схему (см. prepareSchema): ping повторяется
+// opts.ConnectAttempts раз с удваивающейся паузой от opts.ConnectBackoff.
+func (g guard) connect(ctx context.Context, dsn string, ping func(ctx context.Context) error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = g.attempt(ctx, ping)
+		if err == nil {
+			err = prepareSchema(dsn, g.opts)
+		}
+		if err == nil {
+			return nil
+		}
+		// устаревшая схема сама не обновится, ждать бессмысленно
+		if errors.Is(err, ErrSchemaOutdated) || attempt+1 >= g.opts.ConnectAttempts {
+			break
+		}
+		if err := sleep(ctx, backoff(g.opts.ConnectBackoff, attempt)); err != nil {
+			break
+		}
+	}
+
+	return fmt.Errorf("database is not ready: %w", err)
+}
+
+// attempt выполняет op с таймаутом запроса.
+func (g guard) attempt(ctx context.Context, op func(ctx context.Context) error) error {
+	if g.opts.QueryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.opts.QueryTimeout)
+		defer cancel()
+	}
+	return op(ctx)
+}
+
+// run выполняет op через выключатель цепи, повторяя временные ошибки.
+// Пока цепь разомкнута, op не вызывается и возвращается ErrUnavailable.
+func (g guard) run(ctx context.Context, op func(ctx context.Context) error) error {
+	for attempt := 0; ; attempt++ {
+		t, ok := g.breaker.allow()
+		if !ok {
+			metrics.DBCircuitRejections.Inc()
+			return fmt.Errorf("%w: database circuit breaker is open", ErrUnavailable)
+		}
+
+		err := g.attempt(ctx, op)
+		if ctx.Err() != nil {
+			// вызывающий сам отменил запрос, о состоянии базы это ничего не говорит
+			g.breaker.release(t)
+			return err
+		}
+		g.breaker.record(t, isOutage(err))
+
+		if attempt >= g.opts.QueryRetries || !isTransient(err) {
+			return err
+		}
+
+		metrics.DBRetries.Inc()
+		if err := sleep(ctx, backoff(retryBackoff, attempt)); err != nil {
+			return err
+		}
+	}
+}
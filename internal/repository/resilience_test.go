@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDBRepository(opts DBOptions) *DBRepository {
+	return &DBRepository{guard: newGuard(opts)}
+}
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		transient bool
+		outage    bool
+	}{
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, true, true},
+		{"too many connections", &pgconn.PgError{Code: "53300"}, true, true},
+		{"admin shutdown", &pgconn.PgError{Code: "57P01"}, false, true},
+		{"unique violation", &pgconn.PgError{Code: uniqueViolation}, false, false},
+		{"no rows", sql.ErrNoRows, false, false},
+		{"not found", ErrNotFound, false, false},
+		{"query timeout", context.DeadlineExceeded, false, true},
+		{"connect", &pgconn.ConnectError{}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.transient, isTransient(tt.err))
+			assert.Equal(t, tt.outage, isOutage(tt.err))
+		})
+	}
+}
+
+func TestRunRetriesTransientErrors(t *testing.T) {
+	repo := newTestDBRepository(DBOptions{QueryRetries: 2})
+
+	calls := 0
+	err := repo.run(t.Context(), func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return &pgconn.PgError{Code: "40001"}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = repo.run(t.Context(), func(context.Context) error {
+		calls++
+		return &pgconn.PgError{Code: uniqueViolation}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls, "permanent errors are not retried")
+}
+
+func TestRunAppliesQueryTimeout(t *testing.T) {
+	repo := newTestDBRepository(DBOptions{QueryTimeout: 10 * time.Millisecond})
+
+	err := repo.run(t.Context(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRunOpensCircuit(t *testing.T) {
+	repo := newTestDBRepository(DBOptions{BreakerThreshold: 2, BreakerCooldown: time.Hour})
+	down := func(context.Context) error { return &pgconn.ConnectError{} }
+
+	assert.False(t, errors.Is(repo.run(t.Context(), down), ErrUnavailable))
+	assert.False(t, errors.Is(repo.run(t.Context(), down), ErrUnavailable))
+
+	calls := 0
+	err := repo.run(t.Context(), func(context.Context) error {
+		calls++
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Zero(t, calls, "open circuit rejects without a round trip")
+}
+
+func TestRunIgnoresCallerCancellation(t *testing.T) {
+	repo := newTestDBRepository(DBOptions{BreakerThreshold: 1, BreakerCooldown: time.Hour})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	err := repo.run(ctx, func(ctx context.Context) error { return ctx.Err() })
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.NoError(t, repo.run(t.Context(), func(context.Context) error { return nil }))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 100*time.Millisecond, backoff(100*time.Millisecond, 0))
+	assert.Equal(t, 400*time.Millisecond, backoff(100*time.Millisecond, 2))
+	assert.Equal(t, maxBackoff, backoff(time.Second, 10))
+	assert.Equal(t, maxBackoff, backoff(time.Second, 100))
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"cmp"
+	"hash/fnv"
+	"slices"
+	"strconv"
+)
+
+// точек на кольце у каждого шарда: чем больше, тем ровнее распределение
+const ringVnodes = 256
+
+// ring — консистентное хеширование id по шардам. Каждому шарду принадлежит
+// ringVnodes точек кольца, id достается шарду первой точки не меньше хеша id.
+// При добавлении шарда на него переезжает примерно 1/N записей, остальные
+// остаются на месте. Точки зависят только от номера шарда, поэтому шарды
+// можно добавлять лишь в конец списка.
+type ring struct {
+	points []uint64
+	owners []int
+}
+
+func newRing(shards int) *ring {
+	type point struct {
+		hash  uint64
+		owner int
+	}
+
+	all := make([]point, 0, shards*ringVnodes)
+	for shard := range shards {
+		for vnode := range ringVnodes {
+			all = append(all, point{ringHash("shard-" + strconv.Itoa(shard) + "-" + strconv.Itoa(vnode)), shard})
+		}
+	}
+	slices.SortFunc(all, func(a, b point) int {
+		if a.hash != b.hash {
+			return cmp.Compare(a.hash, b.hash)
+		}
+		return a.owner - b.owner
+	})
+
+	r := &ring{points: make([]uint64, len(all)), owners: make([]int, len(all))}
+	for i, p := range all {
+		r.points[i], r.owners[i] = p.hash, p.owner
+	}
+	return r
+}
+
+// locate возвращает номер шарда, которому принадлежит id.
+func (r *ring) locate(id string) int {
+	h := ringHash(id)
+	i, _ := slices.BinarySearch(r.points, h)
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[i]
+}
+
+// shares возвращает долю кольца, принадлежащую каждому шарду.
+func (r *ring) shares(shards int) []float64 {
+	result := make([]float64, shards)
+	prev := r.points[len(r.points)-1]
+	for i, p := range r.points {
+		// дуга (prev, p] принадлежит владельцу p; для первой точки дуга проходит через ноль
+		result[r.owners[i]] += float64(p-prev) / (1 << 64)
+		prev = p
+	}
+	return result
+}
+
+// ringHash — FNV-1a с перемешиванием splitmix64: у коротких похожих id
+// голый FNV дает плохо разбросанные старшие биты.
+func ringHash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
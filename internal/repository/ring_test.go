@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRingBalance(t *testing.T) {
+	r := newRing(4)
+
+	counts := make([]int, 4)
+	for i := range 100000 {
+		counts[r.locate("id"+strconv.Itoa(i))]++
+	}
+	for shard, n := range counts {
+		assert.InDelta(t, 25000, n, 2500, "shard %d", shard)
+	}
+
+	total := 0.0
+	for _, share := range r.shares(4) {
+		assert.InDelta(t, 0.25, share, 0.025)
+		total += share
+	}
+	assert.InDelta(t, 1, total, 1e-9)
+}
+
+func TestRingAddShardMovesOnlyToNewShard(t *testing.T) {
+	before, after := newRing(4), newRing(5)
+
+	moved := 0
+	for i := range 100000 {
+		id := "id" + strconv.Itoa(i)
+		from, to := before.locate(id), after.locate(id)
+		if from != to {
+			assert.Equal(t, 4, to, "records move only to the added shard")
+			moved++
+		}
+	}
+	assert.InDelta(t, 20000, moved, 2500)
+
+	assert.Equal(t, newRing(4).locate("stable"), before.locate("stable"), "placement is deterministic")
+}
@@ -0,0 +1,350 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+)
+
+// ShardedRepository раскладывает записи по шардам Postgres по short через
+// консистентное хеширование. Уникальность original в области, которую шард
+// может проверить только у себя, обеспечивает глобальный индекс url_originals
+// в базе Index.
+//
+// Пока после добавления шардов идет перебалансировка (см. Rebalance), запись,
+// не найденная у нового владельца, ищется у владельца по прежнему кольцу.
+type ShardedRepository struct {
+	Index  *DBRepository
+	Shards []*DBRepository
+
+	ring *ring
+	// кольцо до добавления шардов; nil, если перебалансировка не идет
+	previous *ring
+}
+
+// NewShardedRepository открывает базу индекса и шарды, применяя миграции к
+// каждой. previous — число шардов до последнего добавления, 0 — шарды не менялись.
+func NewShardedRepository(ctx context.Context, indexDSN string, shardDSNs []string, previous int, opts DBOptions) (*ShardedRepository, error) {
+	// реплики задаются для одиночной базы, у шардов их нет
+	opts.Replicas = nil
+
+	index, err := NewDBRepository(ctx, indexDSN, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &ShardedRepository{Index: index, ring: newRing(len(shardDSNs))}
+	if previous > 0 && previous < len(shardDSNs) {
+		repo.previous = newRing(previous)
+	}
+
+	for _, dsn := range shardDSNs {
+		shard, err := NewDBRepository(ctx, dsn, opts)
+		if err != nil {
+			repo.Close()
+			return nil, err
+		}
+		repo.Shards = append(repo.Shards, shard)
+	}
+	return repo, nil
+}
+
+// Close закрывает базу индекса и все шарды.
+func (repo *ShardedRepository) Close() error {
+	errs := []error{repo.Index.Close()}
+	for _, shard := range repo.Shards {
+		errs = append(errs, shard.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (repo *ShardedRepository) shard(id string) *DBRepository {
+	return repo.Shards[repo.ring.locate(id)]
+}
+
+// previousShard возвращает владельца id по прежнему кольцу, если он другой.
+func (repo *ShardedRepository) previousShard(id string) *DBRepository {
+	if repo.previous == nil {
+		return nil
+	}
+	if shard := repo.Shards[repo.previous.locate(id)]; shard != repo.shard(id) {
+		return shard
+	}
+	return nil
+}
+
+// update выполняет изменение записи id у текущего владельца, а если записи
+// там нет — у прежнего. Если и там ее не оказалось, запись могла как раз
+// переехать, поэтому текущий владелец пробуется еще раз.
+func (repo *ShardedRepository) update(id string, op func(shard *DBRepository) error) error {
+	err := op(repo.shard(id))
+	old := repo.previousShard(id)
+	if old == nil || !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	if err := op(old); !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return op(repo.shard(id))
+}
+
+// taken проверяет, что short не занят у прежнего владельца, куда новая запись не попадет.
+func (repo *ShardedRepository) taken(ctx context.Context, short string) error {
+	old := repo.previousShard(short)
+	if old == nil {
+		return nil
+	}
+
+	_, err := old.GetRecord(ctx, short)
+	if err == nil {
+		return ErrAlreadyExists
+	}
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (repo *ShardedRepository) Save(ctx context.Context, record entities.URLRecord) (string, error) {
+	if err := repo.taken(ctx, record.Short); err != nil {
+		return "", err
+	}
+
+	if record.Dedupe {
+		var indexed string
+		err := repo.Index.run(ctx, func(ctx context.Context) error {
+			return repo.Index.DB.QueryRowContext(ctx, stmtIndexOriginal.sql, record.Scope, record.OriginalURL, record.Short).Scan(&indexed)
+		})
+		if err != nil {
+			return "", err
+		}
+		if indexed != record.Short {
+			return indexed, nil
+		}
+	}
+
+	short, err := repo.shard(record.Short).Save(ctx, record)
+	if err != nil {
+		if record.Dedupe {
+			repo.unindex(ctx, []entities.URLRecord{record})
+		}
+		return "", err
+	}
+
+	// шард нашел адрес у себя, а индекса о нем не знал: индекс догоняет шард
+	if short != record.Short {
+		err := repo.Index.exec(ctx, stmtRepointOriginal.sql, record.Scope, record.OriginalURL, record.Short, short)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return "", err
+		}
+	}
+	return short, nil
+}
+
+// unindex убирает из индекса строки записей, которые не удалось сохранить.
+// Выполняется и после отмены запроса, иначе адрес останется занят несуществующим id.
+func (repo *ShardedRepository) unindex(ctx context.Context, records []entities.URLRecord) {
+	ctx = context.WithoutCancel(ctx)
+	for _, r := range records {
+		if r.Dedupe {
+			repo.Index.exec(ctx, stmtUnindexOriginal.sql, r.Scope, r.OriginalURL, r.Short)
+		}
+	}
+}
+
+// BatchSave сначала одной транзакцией занимает адреса в индексе, затем пишет
+// записи пачками по шардам. Запись, чей адрес индекс уже знает, не пишется:
+// возвращается short из индекса. Если шард отказал, вставленные этой пачкой
+// записи и строки индекса удаляются, так что пачка сохраняется целиком или не
+// сохраняется. Записи, сохраненные прежде (пачку повторяют после обрыва),
+// конфликтом не считаются и при откате остаются на месте.
+func (repo *ShardedRepository) BatchSave(ctx context.Context, records []entities.URLRecord) ([]string, error) {
+	shorts, added, err := repo.indexBatch(ctx, records)
+	if err != nil {
+		return nil, err
+	}
+
+	// номера записей по шардам
+	groups := make(map[int][]int)
+	for i, r := range records {
+		if shorts[i] != "" {
+			continue
+		}
+
+		saved, err := repo.savedAtPrevious(ctx, r)
+		if err != nil {
+			repo.unindex(ctx, added)
+			return nil, err
+		}
+		if saved {
+			shorts[i] = r.Short
+			continue
+		}
+		shard := repo.ring.locate(r.Short)
+		groups[shard] = append(groups[shard], i)
+	}
+
+	inserted := make(map[int][]string)
+	for shard, indexes := range groups {
+		group := make([]entities.URLRecord, len(indexes))
+		for j, i := range indexes {
+			group[j] = records[i]
+		}
+
+		saved, done, err := repo.Shards[shard].batchSave(ctx, group)
+		if err != nil {
+			repo.rollback(ctx, inserted)
+			repo.unindex(ctx, added)
+			return nil, err
+		}
+		for j, i := range indexes {
+			shorts[i] = saved[j]
+			if done[j] {
+				inserted[shard] = append(inserted[shard], saved[j])
+			}
+		}
+	}
+
+	// шард нашел адрес у себя, а индекс о нем не знал: индекс догоняет шард, как в Save
+	for _, indexes := range groups {
+		for _, i := range indexes {
+			r := records[i]
+			if !r.Dedupe || shorts[i] == r.Short {
+				continue
+			}
+			err := repo.Index.exec(ctx, stmtRepointOriginal.sql, r.Scope, r.OriginalURL, r.Short, shorts[i])
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return nil, err
+			}
+		}
+	}
+	return shorts, nil
+}
+
+// savedAtPrevious сообщает, что record уже сохранена у прежнего владельца.
+// Другая запись с тем же short там — ErrAlreadyExists.
+func (repo *ShardedRepository) savedAtPrevious(ctx context.Context, record entities.URLRecord) (bool, error) {
+	old := repo.previousShard(record.Short)
+	if old == nil {
+		return false, nil
+	}
+
+	stored, err := old.GetRecord(ctx, record.Short)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if stored.OriginalURL != record.OriginalURL {
+		return false, ErrAlreadyExists
+	}
+	return true, nil
+}
+
+// indexBatch занимает в индексе адреса записей с Dedupe. Для записи, чей адрес
+// уже записан за другим short (в том числе раньше в этой пачке), возвращает
+// этот short, для остальных — пустую строку. added — записи, чьи строки
+// индекса добавлены сейчас: при откате удаляются только они.
+func (repo *ShardedRepository) indexBatch(ctx context.Context, records []entities.URLRecord) (shorts []string, added []entities.URLRecord, err error) {
+	err = repo.Index.run(ctx, func(ctx context.Context) error {
+		shorts, added = make([]string, len(records)), nil
+
+		tx, err := repo.Index.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		for i, r := range records {
+			if !r.Dedupe {
+				continue
+			}
+
+			var indexed string
+			err := tx.QueryRowContext(ctx, stmtInsertOriginal.sql, r.Scope, r.OriginalURL, r.Short).Scan(&indexed)
+			if errors.Is(err, sql.ErrNoRows) {
+				if err := tx.QueryRowContext(ctx, stmtIndexedShort.sql, r.Scope, r.OriginalURL).Scan(&indexed); err != nil {
+					return err
+				}
+				// тот же short: пачку повторяют, запись пишется на шард как обычно
+				if indexed != r.Short {
+					shorts[i] = indexed
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			added = append(added, r)
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return shorts, added, nil
+}
+
+// rollback удаляет записи, вставленные этой пачкой: inserted — short по номерам шардов.
+func (repo *ShardedRepository) rollback(ctx context.Context, inserted map[int][]string) {
+	ctx = context.WithoutCancel(ctx)
+	for shard, shorts := range inserted {
+		repo.Shards[shard].run(ctx, func(ctx context.Context) error {
+			_, err := repo.Shards[shard].DB.ExecContext(ctx, stmtDeleteURLs.sql, shorts)
+			return err
+		})
+	}
+}
+
+func (repo *ShardedRepository) Get(ctx context.Context, id string) (string, bool) {
+	if original, ok := repo.shard(id).Get(ctx, id); ok {
+		return original, true
+	}
+	if old := repo.previousShard(id); old != nil {
+		return old.Get(ctx, id)
+	}
+	return "", false
+}
+
+func (repo *ShardedRepository) GetRecord(ctx context.Context, id string) (entities.URLRecord, error) {
+	record, err := repo.shard(id).GetRecord(ctx, id)
+	if old := repo.previousShard(id); old != nil && errors.Is(err, ErrNotFound) {
+		return old.GetRecord(ctx, id)
+	}
+	return record, err
+}
+
+func (repo *ShardedRepository) SetRules(ctx context.Context, id string, rules []entities.Rule) error {
+	return repo.update(id, func(shard *DBRepository) error {
+		return shard.SetRules(ctx, id, rules)
+	})
+}
+
+func (repo *ShardedRepository) SetVariants(ctx context.Context, id string, variants []entities.Variant) error {
+	return repo.update(id, func(shard *DBRepository) error {
+		return shard.SetVariants(ctx, id, variants)
+	})
+}
+
+func (repo *ShardedRepository) IncrementClicks(ctx context.Context, id string, variant string) error {
+	return repo.update(id, func(shard *DBRepository) error {
+		return shard.IncrementClicks(ctx, id, variant)
+	})
+}
+
+// Ping проверяет базу индекса и все шарды: без любого из них часть ссылок недоступна.
+func (repo *ShardedRepository) Ping(ctx context.Context) bool {
+	if !repo.Index.Ping(ctx) {
+		return false
+	}
+	for _, shard := range repo.Shards {
+		if !shard.Ping(ctx) {
+			return false
+		}
+	}
+	return true
+}
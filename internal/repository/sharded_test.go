@@ -0,0 +1,114 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// shardDSN возвращает DSN отдельной базы name на сервере postgresDSN, создавая ее при необходимости.
+func shardDSN(t testing.TB, name string) string {
+	db, err := sql.Open("pgx", postgresDSN)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var exists bool
+	err = db.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", name).Scan(&exists)
+	require.NoError(t, err)
+	if !exists {
+		_, err = db.Exec("CREATE DATABASE " + name)
+		require.NoError(t, err)
+	}
+
+	u, err := url.Parse(postgresDSN)
+	require.NoError(t, err)
+	u.Path = "/" + name
+	return u.String()
+}
+
+// openSharded открывает хранилище на shards базах; данные в них не очищаются.
+func openSharded(t testing.TB, shards, previous int) *repository.ShardedRepository {
+	dsns := make([]string, shards)
+	for i := range dsns {
+		dsns[i] = shardDSN(t, fmt.Sprintf("goshortener_shard_%d", i))
+	}
+
+	repo, err := repository.NewShardedRepository(context.Background(), postgresDSN, dsns, previous, repository.DefaultDBOptions())
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+// newShardedRepository открывает хранилище на пустых шардах и пустом индексе.
+func newShardedRepository(t testing.TB, shards, previous int) *repository.ShardedRepository {
+	repo := openSharded(t, shards, previous)
+
+	_, err := repo.Index.DB.Exec("TRUNCATE url_originals")
+	require.NoError(t, err)
+	for _, shard := range repo.Shards {
+		_, err := shard.DB.Exec("TRUNCATE urls CASCADE")
+		require.NoError(t, err)
+	}
+	return repo
+}
+
+func TestShardedRebalance(t *testing.T) {
+	if postgresDSN == "" {
+		t.Skip("TEST_DATABASE_DSN or EMBEDDED_POSTGRES is not set")
+	}
+	ctx := t.Context()
+
+	single := newShardedRepository(t, 1, 0)
+	for i := range 50 {
+		_, err := single.Save(ctx, entities.URLRecord{
+			Short:       fmt.Sprintf("id%d", i),
+			OriginalURL: fmt.Sprintf("https://example.com/%d", i),
+			Dedupe:      true,
+			Variants:    []entities.Variant{{Name: "a", URL: "https://example.com/a", Weight: 1}},
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, single.IncrementClicks(ctx, "id7", "a"))
+
+	// шард добавлен, но записи еще не переехали: их находит прежнее кольцо
+	growing := openSharded(t, 3, 1)
+	for i := range 50 {
+		_, err := growing.GetRecord(ctx, fmt.Sprintf("id%d", i))
+		require.NoError(t, err)
+	}
+	require.NoError(t, growing.SetRules(ctx, "id7", []entities.Rule{{Target: "https://example.com/rule"}}))
+
+	stats, err := growing.Rebalance(ctx, 7, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 50, stats.Scanned)
+	assert.Positive(t, stats.Moved)
+
+	again, err := growing.Rebalance(ctx, 7, nil)
+	require.NoError(t, err)
+	assert.Zero(t, again.Moved, "rebalance is idempotent")
+
+	grown := openSharded(t, 3, 0)
+	record, err := grown.GetRecord(ctx, "id7")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), record.Variants[0].Clicks)
+	assert.Len(t, record.Rules, 1)
+
+	short, err := grown.Save(ctx, entities.URLRecord{Short: "dup", OriginalURL: "https://example.com/7", Dedupe: true})
+	require.NoError(t, err)
+	assert.Equal(t, "id7", short, "dedup index survives rebalancing")
+
+	perShard, err := grown.Stats(ctx)
+	require.NoError(t, err)
+	var total int64
+	for _, s := range perShard {
+		total += s.Records
+	}
+	assert.Equal(t, int64(50), total)
+}
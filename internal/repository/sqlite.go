@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteScheme — префикс DATABASE_DSN, выбирающий SQLite: sqlite://путь/к/файлу.db
+const sqliteScheme = "sqlite://"
+
+func IsSQLiteDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, sqliteScheme)
+}
+
+// Настройки каждого соединения: WAL, чтобы чтение не ждало записи; внешние
+// ключи для каскадного удаления вариантов; ожидание занятой базы вместо
+// немедленной ошибки SQLITE_BUSY.
+var sqlitePragmas = []string{
+	"journal_mode(WAL)",
+	"synchronous(NORMAL)",
+	"foreign_keys(1)",
+	"busy_timeout(5000)",
+}
+
+// Запросы, которые в SQLite пишутся иначе, чем в queries.go. Остальные
+// запросы Postgres подходят как есть: SQLite понимает $N, RETURNING и
+// ON CONFLICT по частичному индексу.
+const (
+	sqliteGetRecord = `SELECT short, original, created_at, scope, dedupe, ` + redirectColumns + `, rules,
+		(
+			SELECT json_group_array(json_object('name', name, 'url', url, 'weight', weight, 'clicks', clicks) ORDER BY position)
+			FROM url_variants v WHERE v.short = urls.short
+		)
+	FROM urls WHERE short=$1`
+	// транзакции открываются как BEGIN IMMEDIATE, поэтому блокировка строки не нужна
+	sqliteFindURL = `SELECT true FROM urls WHERE short = $1`
+)
+
+// SQLiteRepository — хранилище в файле SQLite для развертывания одним
+// бинарником. Драйвер написан на чистом Go, CGO не нужен. Схема и миграции
+// те же, что у Postgres (migrations/sqlite), семантика методов совпадает с
+// DBRepository.
+type SQLiteRepository struct {
+	DB  *sql.DB
+	DSN string
+}
+
+func NewSQLiteRepository(ctx context.Context, DSN string, opts DBOptions) (*SQLiteRepository, error) {
+	path, query, _ := strings.Cut(strings.TrimPrefix(DSN, sqliteScheme), "?")
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	for _, pragma := range sqlitePragmas {
+		params.Add("_pragma", pragma)
+	}
+	// запись начинается с блокировки базы, а не с попытки повысить ее посреди транзакции
+	params.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := prepareSchema(DSN, opts); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteRepository{DB: db, DSN: DSN}, nil
+}
+
+func (repo *SQLiteRepository) Close() error {
+	return repo.DB.Close()
+}
+
+// translateSQLiteError приводит нарушение уникальности к ошибкам пакета repository.
+func translateSQLiteError(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code() != sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return err
+	}
+
+	// SQLite не называет индекс, а перечисляет его колонки:
+	// "UNIQUE constraint failed: urls.scope, urls.original"
+	if strings.Contains(sqliteErr.Error(), "urls.original") {
+		return ErrOriginalExists
+	}
+	return ErrAlreadyExists
+}
+
+func (repo *SQLiteRepository) Save(ctx context.Context, record entities.URLRecord) (string, error) {
+	if len(record.Variants) == 0 {
+		short, err := save(ctx, repo.DB, record)
+		return short, translateSQLiteError(err)
+	}
+
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	short, err := save(ctx, tx, record)
+	if err == nil && short == record.Short {
+		err = insertVariants(ctx, tx, short, record.Variants)
+	}
+	if err != nil {
+		return "", translateSQLiteError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return short, nil
+}
+
+func (repo *SQLiteRepository) BatchSave(ctx context.Context, records []entities.URLRecord) ([]string, error) {
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	shorts, _, err := insertRecords(ctx, tx, records)
+	if err != nil {
+		return nil, translateSQLiteError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return shorts, nil
+}
+
+func (repo *SQLiteRepository) Get(ctx context.Context, id string) (string, bool) {
+	var fullURL string
+	if err := repo.DB.QueryRowContext(ctx, stmtGetOriginal.sql, id).Scan(&fullURL); err != nil {
+		return "", false
+	}
+	return fullURL, true
+}
+
+func (repo *SQLiteRepository) GetRecord(ctx context.Context, id string) (entities.URLRecord, error) {
+	return getRecord(ctx, repo.DB, sqliteGetRecord, id)
+}
+
+// exec выполняет изменяющий запрос и возвращает ErrNotFound, если он не затронул ни одной строки.
+func (repo *SQLiteRepository) exec(ctx context.Context, query string, args ...any) error {
+	result, err := repo.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (repo *SQLiteRepository) SetRules(ctx context.Context, id string, rules []entities.Rule) error {
+	if rules == nil {
+		rules = []entities.Rule{}
+	}
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+
+	return repo.exec(ctx, stmtSetRules.sql, id, string(rulesJSON))
+}
+
+func (repo *SQLiteRepository) SetVariants(ctx context.Context, id string, variants []entities.Variant) error {
+	tx, err := repo.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, sqliteFindURL, id).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, stmtVariantClicks.sql, id)
+	if err != nil {
+		return err
+	}
+	var old []entities.Variant
+	for rows.Next() {
+		var v entities.Variant
+		if err := rows.Scan(&v.Name, &v.Clicks); err != nil {
+			rows.Close()
+			return err
+		}
+		old = append(old, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, stmtDeleteVariants.sql, id); err != nil {
+		return err
+	}
+	if err := insertVariants(ctx, tx, id, carryClicks(old, variants)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repo *SQLiteRepository) IncrementClicks(ctx context.Context, id string, variant string) error {
+	return repo.exec(ctx, stmtIncrementClicks.sql, id, variant)
+}
+
+func (repo *SQLiteRepository) Ping(ctx context.Context) bool {
+	return repo.DB.PingContext(ctx) == nil
+}
@@ -0,0 +1,64 @@
+package rules
+
+import "strings"
+
+const (
+	DeviceIOS     = "ios"
+	DeviceAndroid = "android"
+	// прочие мобильные устройства; в условиях mobile совпадает также с ios и android
+	DeviceMobile  = "mobile"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+)
+
+var devices = map[string]bool{
+	DeviceIOS:     true,
+	DeviceAndroid: true,
+	DeviceMobile:  true,
+	DeviceDesktop: true,
+	DeviceBot:     true,
+}
+
+// подстроки User-Agent (в нижнем регистре), по которым узнаются роботы и сборщики превью
+var botMarkers = []string{"bot", "crawler", "spider", "slurp", "facebookexternalhit", "preview", "curl/", "wget/"}
+
+var mobileMarkers = []string{"mobile", "windows phone", "opera mini", "blackberry"}
+
+// ClassifyDevice относит User-Agent к одному из классов устройств.
+func ClassifyDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	switch {
+	case containsAny(ua, botMarkers):
+		return DeviceBot
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return DeviceIOS
+	case strings.Contains(ua, "android"):
+		return DeviceAndroid
+	case containsAny(ua, mobileMarkers):
+		return DeviceMobile
+	default:
+		return DeviceDesktop
+	}
+}
+
+func matchDevice(device string, allowed []string) bool {
+	for _, d := range allowed {
+		if d == device {
+			return true
+		}
+		if d == DeviceMobile && (device == DeviceIOS || device == DeviceAndroid) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(s string, markers []string) bool {
+	for _, m := range markers {
+		if strings.Contains(s, m) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyDevice(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want string
+	}{
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148", DeviceIOS},
+		{"Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15", DeviceIOS},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36", DeviceAndroid},
+		{"Mozilla/5.0 (Windows Phone 10.0; Android 6.0.1) Edge/15.15063", DeviceAndroid},
+		{"Opera/9.80 (J2ME/MIDP; Opera Mini/9.80) Presto/2.5.25", DeviceMobile},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36", DeviceDesktop},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", DeviceBot},
+		{"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", DeviceBot},
+		{"curl/8.4.0", DeviceBot},
+		{"", DeviceDesktop},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ClassifyDevice(tt.ua), tt.ua)
+	}
+}
+
+func TestMatchDevice(t *testing.T) {
+	assert.True(t, matchDevice(DeviceIOS, []string{DeviceIOS}))
+	assert.True(t, matchDevice(DeviceIOS, []string{DeviceMobile}))
+	assert.True(t, matchDevice(DeviceAndroid, []string{DeviceDesktop, DeviceMobile}))
+	assert.False(t, matchDevice(DeviceDesktop, []string{DeviceMobile}))
+	assert.False(t, matchDevice(DeviceMobile, []string{DeviceIOS, DeviceAndroid}))
+}
@@ -0,0 +1,48 @@
+package rules
+
+import (
+	"net"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// GeoResolver определяет страну по IP-адресу. Пустая строка — страна неизвестна.
+type GeoResolver interface {
+	Country(ip net.IP) string
+}
+
+// GeoIP читает страну из локальной базы MaxMind (GeoLite2-Country, GeoIP2-Country или City).
+type GeoIP struct {
+	reader *maxminddb.Reader
+}
+
+func OpenGeoIP(path string) (*GeoIP, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GeoIP{reader: reader}, nil
+}
+
+func (g *GeoIP) Country(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+	if err := g.reader.Lookup(ip, &record); err != nil {
+		return ""
+	}
+
+	return strings.ToUpper(record.Country.ISOCode)
+}
+
+func (g *GeoIP) Close() error {
+	return g.reader.Close()
+}
@@ -0,0 +1,49 @@
+package rules
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parseAcceptLanguage возвращает языковые теги из Accept-Language с q > 0
+// в нижнем регистре. Порядок не важен: правило либо подходит, либо нет.
+func parseAcceptLanguage(header string) []string {
+	var tags []string
+
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(p), "=")
+			if ok && strings.TrimSpace(key) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// matchLanguage проверяет, что хотя бы один тег клиента попадает в один из диапазонов.
+// Диапазон en подходит для en, en-US и en-GB, диапазон en-US — только для en-US.
+func matchLanguage(tags []string, ranges []string) bool {
+	for _, r := range ranges {
+		r = strings.ToLower(r)
+		for _, tag := range tags {
+			if tag == r || strings.HasPrefix(tag, r+"-") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,23 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, []string{"ru-ru", "ru", "en"}, parseAcceptLanguage("ru-RU, ru;q=0.9, en;q=0.5, de;q=0, *;q=0.1"))
+	assert.Empty(t, parseAcceptLanguage(""))
+}
+
+func TestMatchLanguage(t *testing.T) {
+	tags := parseAcceptLanguage("pt-BR,en;q=0.3")
+
+	assert.True(t, matchLanguage(tags, []string{"pt"}))
+	assert.True(t, matchLanguage(tags, []string{"PT-br"}))
+	assert.True(t, matchLanguage(tags, []string{"de", "en"}))
+	assert.False(t, matchLanguage(tags, []string{"pt-PT"}))
+	assert.False(t, matchLanguage(tags, []string{"e"}))
+	assert.False(t, matchLanguage(nil, []string{"en"}))
+}
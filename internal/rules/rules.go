@@ -0,0 +1,151 @@
+// Package rules выбирает адрес назначения по условиям правил ссылки.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+)
+
+// максимальное число правил у одной ссылки
+const MaxRules = 50
+
+var ErrInvalidRule = errors.New("invalid rule")
+
+// Request — признаки запроса, по которым проверяются условия.
+type Request struct {
+	Device string
+	// теги Accept-Language в нижнем регистре
+	Languages []string
+	// код страны в верхнем регистре, пустой — неизвестна
+	Country string
+	Time    time.Time
+}
+
+// NewRequest собирает признаки из HTTP-запроса. Страна определяется только
+// при geo != nil и только если она нужна хотя бы одному правилу.
+func NewRequest(r *http.Request, list []entities.Rule, geo GeoResolver, now time.Time) Request {
+	req := Request{
+		Device:    ClassifyDevice(r.UserAgent()),
+		Languages: parseAcceptLanguage(r.Header.Get("Accept-Language")),
+		Time:      now,
+	}
+
+	if geo != nil && needsCountry(list) {
+		// X-Forwarded-For не учитывается: без доверенного прокси его подделывает кто угодно
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		req.Country = geo.Country(net.ParseIP(host))
+	}
+
+	return req
+}
+
+func needsCountry(list []entities.Rule) bool {
+	for _, rule := range list {
+		if len(rule.Conditions.Countries) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Match проверяет, что запрос удовлетворяет всем условиям правила.
+func Match(c entities.RuleConditions, req Request) bool {
+	if len(c.Devices) > 0 && !matchDevice(req.Device, c.Devices) {
+		return false
+	}
+	if len(c.Languages) > 0 && !matchLanguage(req.Languages, c.Languages) {
+		return false
+	}
+	if len(c.Countries) > 0 && !matchCountry(req.Country, c.Countries) {
+		return false
+	}
+	if !matchTime(req.Time, c.From, c.Until) {
+		return false
+	}
+	return true
+}
+
+// Evaluate возвращает адрес первого подходящего правила.
+func Evaluate(list []entities.Rule, req Request) (string, bool) {
+	for _, rule := range list {
+		if Match(rule.Conditions, req) {
+			return rule.Target, true
+		}
+	}
+	return "", false
+}
+
+func matchCountry(country string, allowed []string) bool {
+	if country == "" {
+		return false
+	}
+	for _, c := range allowed {
+		if strings.EqualFold(c, country) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchTime(now time.Time, from, until *time.Time) bool {
+	if from != nil && now.Before(*from) {
+		return false
+	}
+	if until != nil && !now.Before(*until) {
+		return false
+	}
+	return true
+}
+
+// Validate проверяет список правил целиком и сообщает номер первого некорректного.
+func Validate(list []entities.Rule) error {
+	if len(list) > MaxRules {
+		return fmt.Errorf("%w: at most %d rules allowed, got %d", ErrInvalidRule, MaxRules, len(list))
+	}
+
+	for i, rule := range list {
+		if err := validateRule(rule); err != nil {
+			return fmt.Errorf("%w: rule %d: %s", ErrInvalidRule, i, err)
+		}
+	}
+	return nil
+}
+
+func validateRule(rule entities.Rule) error {
+	u, err := url.Parse(rule.Target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("target %q must be an absolute http(s) url", rule.Target)
+	}
+
+	c := rule.Conditions
+	for _, d := range c.Devices {
+		if !devices[d] {
+			return fmt.Errorf("unknown device %q", d)
+		}
+	}
+	for _, l := range c.Languages {
+		if l == "" || strings.ContainsAny(l, ",; ") {
+			return fmt.Errorf("invalid language range %q", l)
+		}
+	}
+	for _, country := range c.Countries {
+		if len(country) != 2 {
+			return fmt.Errorf("country %q must be an ISO 3166-1 alpha-2 code", country)
+		}
+	}
+	if c.From != nil && c.Until != nil && !c.From.Before(*c.Until) {
+		return errors.New("from must be before until")
+	}
+
+	return nil
+}
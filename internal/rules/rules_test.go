@@ -0,0 +1,110 @@
+package rules
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeGeo map[string]string
+
+func (g fakeGeo) Country(ip net.IP) string {
+	return g[ip.String()]
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestMatchCountry(t *testing.T) {
+	assert.True(t, matchCountry("DE", []string{"at", "de"}))
+	assert.False(t, matchCountry("FR", []string{"DE"}))
+	assert.False(t, matchCountry("", []string{"DE"}))
+}
+
+func TestMatchTime(t *testing.T) {
+	from := time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC)
+	until := from.Add(24 * time.Hour)
+
+	assert.True(t, matchTime(from, &from, &until))
+	assert.True(t, matchTime(until.Add(-time.Second), &from, &until))
+	assert.False(t, matchTime(until, &from, &until))
+	assert.False(t, matchTime(from.Add(-time.Second), &from, nil))
+	assert.True(t, matchTime(from, nil, nil))
+}
+
+func TestEvaluateTakesFirstMatch(t *testing.T) {
+	list := []entities.Rule{
+		{Conditions: entities.RuleConditions{Devices: []string{DeviceIOS}}, Target: "https://apps.apple.com/app"},
+		{Conditions: entities.RuleConditions{Devices: []string{DeviceAndroid}}, Target: "https://play.google.com/app"},
+		{Conditions: entities.RuleConditions{Countries: []string{"DE"}, Languages: []string{"de"}}, Target: "https://example.de"},
+		{Conditions: entities.RuleConditions{Devices: []string{DeviceMobile}}, Target: "https://m.example.com"},
+	}
+
+	target, ok := Evaluate(list, Request{Device: DeviceIOS})
+	assert.True(t, ok)
+	assert.Equal(t, "https://apps.apple.com/app", target)
+
+	target, _ = Evaluate(list, Request{Device: DeviceDesktop, Country: "DE", Languages: []string{"de-de"}})
+	assert.Equal(t, "https://example.de", target)
+
+	_, ok = Evaluate(list, Request{Device: DeviceDesktop, Country: "DE", Languages: []string{"en"}})
+	assert.False(t, ok)
+
+	target, _ = Evaluate(list, Request{Device: DeviceMobile})
+	assert.Equal(t, "https://m.example.com", target)
+}
+
+func TestNewRequest(t *testing.T) {
+	list := []entities.Rule{{Conditions: entities.RuleConditions{Countries: []string{"NL"}}, Target: "https://example.nl"}}
+
+	r := httptest.NewRequest("GET", "/abc", nil)
+	r.RemoteAddr = "203.0.113.7:5555"
+	r.Header.Set("User-Agent", "Mozilla/5.0 (Linux; Android 14)")
+	r.Header.Set("Accept-Language", "nl-NL,nl;q=0.8")
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	now := time.Now()
+	req := NewRequest(r, list, fakeGeo{"203.0.113.7": "NL", "198.51.100.1": "US"}, now)
+	assert.Equal(t, Request{Device: DeviceAndroid, Languages: []string{"nl-nl", "nl"}, Country: "NL", Time: now}, req)
+
+	req = NewRequest(r, nil, fakeGeo{"203.0.113.7": "NL"}, now)
+	assert.Empty(t, req.Country, "country is resolved only when a rule needs it")
+
+	req = NewRequest(r, list, nil, now)
+	assert.Empty(t, req.Country)
+}
+
+func TestValidate(t *testing.T) {
+	valid := entities.Rule{Conditions: entities.RuleConditions{Devices: []string{DeviceIOS}}, Target: "https://apps.apple.com/app"}
+	assert.NoError(t, Validate([]entities.Rule{valid}))
+	assert.NoError(t, Validate(nil))
+
+	from := time.Now()
+	invalid := []entities.Rule{
+		{Target: "/relative"},
+		{Target: "javascript:alert(1)"},
+		{Conditions: entities.RuleConditions{Devices: []string{"tablet"}}, Target: "https://example.com"},
+		{Conditions: entities.RuleConditions{Countries: []string{"DEU"}}, Target: "https://example.com"},
+		{Conditions: entities.RuleConditions{Languages: []string{"en,de"}}, Target: "https://example.com"},
+		{Conditions: entities.RuleConditions{From: ptr(from), Until: ptr(from)}, Target: "https://example.com"},
+	}
+	for _, rule := range invalid {
+		assert.ErrorIs(t, Validate([]entities.Rule{valid, rule}), ErrInvalidRule, rule.Target)
+	}
+
+	tooMany := make([]entities.Rule, MaxRules+1)
+	for i := range tooMany {
+		tooMany[i] = valid
+	}
+	assert.ErrorIs(t, Validate(tooMany), ErrInvalidRule)
+}
+
+func TestOpenGeoIPMissingFile(t *testing.T) {
+	_, err := OpenGeoIP(t.TempDir() + "/missing.mmdb")
+	assert.Error(t, err)
+}
@@ -1,13 +1,60 @@
 package serializers
 
+import "time"
+
 //easyjson:json
 type Request struct {
 	URL string `json:"url"`
+	RedirectOptions
+	// варианты A/B-теста; если заданы, url служит только для справки
+	Variants VariantSlice `json:"variants,omitempty"`
+	DedupeOptions
+}
+
+// DedupeOptions управляют повторным сокращением того же адреса.
+type DedupeOptions struct {
+	// false — всегда создавать новую ссылку; по умолчанию true
+	Dedupe *bool `json:"dedupe,omitempty"`
+	// область дедупликации, например id пользователя; пустая — общая
+	Scope string `json:"scope,omitempty"`
+}
+
+// RedirectOptions — необязательные параметры редиректа, задаваемые при создании ссылки.
+type RedirectOptions struct {
+	RedirectCode int `json:"redirect_code,omitempty"`
+	// 0 — без заголовков кеширования; если не задано, действует глобальная настройка
+	CacheMaxAge    *int   `json:"cache_max_age,omitempty"`
+	ReferrerPolicy string `json:"referrer_policy,omitempty"`
+	// ignore, append, override или allowlist
+	QueryMode      string            `json:"query_mode,omitempty"`
+	QueryAllowlist []string          `json:"query_allowlist,omitempty"`
+	DefaultParams  map[string]string `json:"default_params,omitempty"`
+	// ссылка пересылает суффикс пути: /{id}/a/b -> <url>/a/b
+	Prefix bool `json:"prefix,omitempty"`
 }
 
 //easyjson:json
 type Response struct {
-	Result string `json:"result"`
+	Result string `json:"result" msgpack:"result"`
+}
+
+func (r Response) Text() string {
+	return r.Result
+}
+
+// --- Link DTO for /api/v2 ---
+//
+//easyjson:json
+type Link struct {
+	ID          string     `json:"id" msgpack:"id"`
+	ShortURL    string     `json:"short_url" msgpack:"short_url"`
+	OriginalURL string     `json:"original_url" msgpack:"original_url"`
+	CreatedAt   time.Time  `json:"created_at" msgpack:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at" msgpack:"expires_at"`
+}
+
+func (l Link) Text() string {
+	return l.ShortURL
 }
 
 // --- Request DTO for batch ---
@@ -16,6 +63,9 @@ type Response struct {
 type BatchRequestItem struct {
 	CorrelationID string `json:"correlation_id"`
 	OriginalURL   string `json:"original_url"`
+	RedirectOptions
+	Variants VariantSlice `json:"variants,omitempty"`
+	DedupeOptions
 }
 
 // --- Response DTO for batch ---
@@ -31,3 +81,60 @@ type BatchRequestItemSlice []BatchRequestItem
 
 //easyjson:json
 type BatchResponseItemSlice []BatchResponseItem
+
+// --- Link metadata for /api/urls/{id} ---
+//
+//easyjson:json
+type URLInfo struct {
+	ID          string    `json:"id"`
+	ShortURL    string    `json:"short_url"`
+	OriginalURL string    `json:"original_url"`
+	CreatedAt   time.Time `json:"created_at"`
+	Status      string    `json:"status"`
+}
+
+// --- Conditional redirect rules ---
+//
+//easyjson:json
+type Rule struct {
+	When   RuleConditions `json:"when"`
+	Target string         `json:"target"`
+}
+
+type RuleConditions struct {
+	Device   []string   `json:"device,omitempty"`
+	Language []string   `json:"language,omitempty"`
+	Country  []string   `json:"country,omitempty"`
+	From     *time.Time `json:"from,omitempty"`
+	Until    *time.Time `json:"until,omitempty"`
+}
+
+//easyjson:json
+type RuleSlice []Rule
+
+// --- A/B variants ---
+//
+//easyjson:json
+type Variant struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+	// только в ответах, при записи игнорируется
+	Clicks int64 `json:"clicks"`
+}
+
+//easyjson:json
+type VariantSlice []Variant
+
+// --- Problem details (RFC 7807) ---
+//
+//easyjson:json
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
@@ -7,6 +7,7 @@ import (
 	easyjson "github.com/mailru/easyjson"
 	jlexer "github.com/mailru/easyjson/jlexer"
 	jwriter "github.com/mailru/easyjson/jwriter"
+	time "time"
 )
 
 // suppress unused package warning
@@ -17,7 +18,962 @@ var (
 	_ easyjson.Marshaler
 )
 
-func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers(in *jlexer.Lexer, out *Response) {
+func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers(in *jlexer.Lexer, out *VariantSlice) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		in.Skip()
+		*out = nil
+	} else {
+		in.Delim('[')
+		if *out == nil {
+			if !in.IsDelim(']') {
+				*out = make(VariantSlice, 0, 1)
+			} else {
+				*out = VariantSlice{}
+			}
+		} else {
+			*out = (*out)[:0]
+		}
+		for !in.IsDelim(']') {
+			var v1 Variant
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				(v1).UnmarshalEasyJSON(in)
+			}
+			*out = append(*out, v1)
+			in.WantComma()
+		}
+		in.Delim(']')
+	}
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers(out *jwriter.Writer, in VariantSlice) {
+	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v2, v3 := range in {
+			if v2 > 0 {
+				out.RawByte(',')
+			}
+			(v3).MarshalEasyJSON(out)
+		}
+		out.RawByte(']')
+	}
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v VariantSlice) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v VariantSlice) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *VariantSlice) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *VariantSlice) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers(l, v)
+}
+func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers1(in *jlexer.Lexer, out *Variant) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		switch key {
+		case "name":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Name = string(in.String())
+			}
+		case "url":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.URL = string(in.String())
+			}
+		case "weight":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Weight = int(in.Int())
+			}
+		case "clicks":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Clicks = int64(in.Int64())
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers1(out *jwriter.Writer, in Variant) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"name\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Name))
+	}
+	{
+		const prefix string = ",\"url\":"
+		out.RawString(prefix)
+		out.String(string(in.URL))
+	}
+	{
+		const prefix string = ",\"weight\":"
+		out.RawString(prefix)
+		out.Int(int(in.Weight))
+	}
+	{
+		const prefix string = ",\"clicks\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.Clicks))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Variant) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Variant) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Variant) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Variant) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers1(l, v)
+}
+func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers2(in *jlexer.Lexer, out *URLInfo) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		switch key {
+		case "id":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.ID = string(in.String())
+			}
+		case "short_url":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.ShortURL = string(in.String())
+			}
+		case "original_url":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.OriginalURL = string(in.String())
+			}
+		case "created_at":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				if data := in.Raw(); in.Ok() {
+					in.AddError((out.CreatedAt).UnmarshalJSON(data))
+				}
+			}
+		case "status":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Status = string(in.String())
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers2(out *jwriter.Writer, in URLInfo) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"id\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.ID))
+	}
+	{
+		const prefix string = ",\"short_url\":"
+		out.RawString(prefix)
+		out.String(string(in.ShortURL))
+	}
+	{
+		const prefix string = ",\"original_url\":"
+		out.RawString(prefix)
+		out.String(string(in.OriginalURL))
+	}
+	{
+		const prefix string = ",\"created_at\":"
+		out.RawString(prefix)
+		out.Raw((in.CreatedAt).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"status\":"
+		out.RawString(prefix)
+		out.String(string(in.Status))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v URLInfo) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers2(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v URLInfo) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers2(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *URLInfo) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers2(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *URLInfo) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers2(l, v)
+}
+func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers3(in *jlexer.Lexer, out *RuleSlice) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		in.Skip()
+		*out = nil
+	} else {
+		in.Delim('[')
+		if *out == nil {
+			if !in.IsDelim(']') {
+				*out = make(RuleSlice, 0, 0)
+			} else {
+				*out = RuleSlice{}
+			}
+		} else {
+			*out = (*out)[:0]
+		}
+		for !in.IsDelim(']') {
+			var v4 Rule
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				(v4).UnmarshalEasyJSON(in)
+			}
+			*out = append(*out, v4)
+			in.WantComma()
+		}
+		in.Delim(']')
+	}
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers3(out *jwriter.Writer, in RuleSlice) {
+	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v5, v6 := range in {
+			if v5 > 0 {
+				out.RawByte(',')
+			}
+			(v6).MarshalEasyJSON(out)
+		}
+		out.RawByte(']')
+	}
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v RuleSlice) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers3(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v RuleSlice) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers3(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *RuleSlice) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers3(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *RuleSlice) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers3(l, v)
+}
+func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers4(in *jlexer.Lexer, out *Rule) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		switch key {
+		case "when":
+			easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers5(in, &out.When)
+		case "target":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Target = string(in.String())
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers4(out *jwriter.Writer, in Rule) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"when\":"
+		out.RawString(prefix[1:])
+		easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers5(out, in.When)
+	}
+	{
+		const prefix string = ",\"target\":"
+		out.RawString(prefix)
+		out.String(string(in.Target))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Rule) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers4(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Rule) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers4(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Rule) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers4(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Rule) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers4(l, v)
+}
+func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers5(in *jlexer.Lexer, out *RuleConditions) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		switch key {
+		case "device":
+			if in.IsNull() {
+				in.Skip()
+				out.Device = nil
+			} else {
+				in.Delim('[')
+				if out.Device == nil {
+					if !in.IsDelim(']') {
+						out.Device = make([]string, 0, 4)
+					} else {
+						out.Device = []string{}
+					}
+				} else {
+					out.Device = (out.Device)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v7 string
+					if in.IsNull() {
+						in.Skip()
+					} else {
+						v7 = string(in.String())
+					}
+					out.Device = append(out.Device, v7)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "language":
+			if in.IsNull() {
+				in.Skip()
+				out.Language = nil
+			} else {
+				in.Delim('[')
+				if out.Language == nil {
+					if !in.IsDelim(']') {
+						out.Language = make([]string, 0, 4)
+					} else {
+						out.Language = []string{}
+					}
+				} else {
+					out.Language = (out.Language)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v8 string
+					if in.IsNull() {
+						in.Skip()
+					} else {
+						v8 = string(in.String())
+					}
+					out.Language = append(out.Language, v8)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "country":
+			if in.IsNull() {
+				in.Skip()
+				out.Country = nil
+			} else {
+				in.Delim('[')
+				if out.Country == nil {
+					if !in.IsDelim(']') {
+						out.Country = make([]string, 0, 4)
+					} else {
+						out.Country = []string{}
+					}
+				} else {
+					out.Country = (out.Country)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v9 string
+					if in.IsNull() {
+						in.Skip()
+					} else {
+						v9 = string(in.String())
+					}
+					out.Country = append(out.Country, v9)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "from":
+			if in.IsNull() {
+				in.Skip()
+				out.From = nil
+			} else {
+				if out.From == nil {
+					out.From = new(time.Time)
+				}
+				if in.IsNull() {
+					in.Skip()
+				} else {
+					if data := in.Raw(); in.Ok() {
+						in.AddError((*out.From).UnmarshalJSON(data))
+					}
+				}
+			}
+		case "until":
+			if in.IsNull() {
+				in.Skip()
+				out.Until = nil
+			} else {
+				if out.Until == nil {
+					out.Until = new(time.Time)
+				}
+				if in.IsNull() {
+					in.Skip()
+				} else {
+					if data := in.Raw(); in.Ok() {
+						in.AddError((*out.Until).UnmarshalJSON(data))
+					}
+				}
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers5(out *jwriter.Writer, in RuleConditions) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if len(in.Device) != 0 {
+		const prefix string = ",\"device\":"
+		first = false
+		out.RawString(prefix[1:])
+		{
+			out.RawByte('[')
+			for v10, v11 := range in.Device {
+				if v10 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v11))
+			}
+			out.RawByte(']')
+		}
+	}
+	if len(in.Language) != 0 {
+		const prefix string = ",\"language\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		{
+			out.RawByte('[')
+			for v12, v13 := range in.Language {
+				if v12 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v13))
+			}
+			out.RawByte(']')
+		}
+	}
+	if len(in.Country) != 0 {
+		const prefix string = ",\"country\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		{
+			out.RawByte('[')
+			for v14, v15 := range in.Country {
+				if v14 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v15))
+			}
+			out.RawByte(']')
+		}
+	}
+	if in.From != nil {
+		const prefix string = ",\"from\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Raw((*in.From).MarshalJSON())
+	}
+	if in.Until != nil {
+		const prefix string = ",\"until\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Raw((*in.Until).MarshalJSON())
+	}
+	out.RawByte('}')
+}
+func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers6(in *jlexer.Lexer, out *Response) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		switch key {
+		case "result":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Result = string(in.String())
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers6(out *jwriter.Writer, in Response) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"result\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Result))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Response) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers6(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Response) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers6(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Response) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers6(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Response) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers6(l, v)
+}
+func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers7(in *jlexer.Lexer, out *Request) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		switch key {
+		case "url":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.URL = string(in.String())
+			}
+		case "variants":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				(out.Variants).UnmarshalEasyJSON(in)
+			}
+		case "dedupe":
+			if in.IsNull() {
+				in.Skip()
+				out.Dedupe = nil
+			} else {
+				if out.Dedupe == nil {
+					out.Dedupe = new(bool)
+				}
+				if in.IsNull() {
+					in.Skip()
+				} else {
+					*out.Dedupe = bool(in.Bool())
+				}
+			}
+		case "scope":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Scope = string(in.String())
+			}
+		case "redirect_code":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.RedirectCode = int(in.Int())
+			}
+		case "cache_max_age":
+			if in.IsNull() {
+				in.Skip()
+				out.CacheMaxAge = nil
+			} else {
+				if out.CacheMaxAge == nil {
+					out.CacheMaxAge = new(int)
+				}
+				if in.IsNull() {
+					in.Skip()
+				} else {
+					*out.CacheMaxAge = int(in.Int())
+				}
+			}
+		case "referrer_policy":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.ReferrerPolicy = string(in.String())
+			}
+		case "query_mode":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.QueryMode = string(in.String())
+			}
+		case "query_allowlist":
+			if in.IsNull() {
+				in.Skip()
+				out.QueryAllowlist = nil
+			} else {
+				in.Delim('[')
+				if out.QueryAllowlist == nil {
+					if !in.IsDelim(']') {
+						out.QueryAllowlist = make([]string, 0, 4)
+					} else {
+						out.QueryAllowlist = []string{}
+					}
+				} else {
+					out.QueryAllowlist = (out.QueryAllowlist)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v16 string
+					if in.IsNull() {
+						in.Skip()
+					} else {
+						v16 = string(in.String())
+					}
+					out.QueryAllowlist = append(out.QueryAllowlist, v16)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "default_params":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				if !in.IsDelim('}') {
+					out.DefaultParams = make(map[string]string)
+				} else {
+					out.DefaultParams = nil
+				}
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v17 string
+					if in.IsNull() {
+						in.Skip()
+					} else {
+						v17 = string(in.String())
+					}
+					(out.DefaultParams)[key] = v17
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		case "prefix":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Prefix = bool(in.Bool())
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers7(out *jwriter.Writer, in Request) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"url\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.URL))
+	}
+	if len(in.Variants) != 0 {
+		const prefix string = ",\"variants\":"
+		out.RawString(prefix)
+		(in.Variants).MarshalEasyJSON(out)
+	}
+	if in.Dedupe != nil {
+		const prefix string = ",\"dedupe\":"
+		out.RawString(prefix)
+		out.Bool(bool(*in.Dedupe))
+	}
+	if in.Scope != "" {
+		const prefix string = ",\"scope\":"
+		out.RawString(prefix)
+		out.String(string(in.Scope))
+	}
+	if in.RedirectCode != 0 {
+		const prefix string = ",\"redirect_code\":"
+		out.RawString(prefix)
+		out.Int(int(in.RedirectCode))
+	}
+	if in.CacheMaxAge != nil {
+		const prefix string = ",\"cache_max_age\":"
+		out.RawString(prefix)
+		out.Int(int(*in.CacheMaxAge))
+	}
+	if in.ReferrerPolicy != "" {
+		const prefix string = ",\"referrer_policy\":"
+		out.RawString(prefix)
+		out.String(string(in.ReferrerPolicy))
+	}
+	if in.QueryMode != "" {
+		const prefix string = ",\"query_mode\":"
+		out.RawString(prefix)
+		out.String(string(in.QueryMode))
+	}
+	if len(in.QueryAllowlist) != 0 {
+		const prefix string = ",\"query_allowlist\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('[')
+			for v18, v19 := range in.QueryAllowlist {
+				if v18 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v19))
+			}
+			out.RawByte(']')
+		}
+	}
+	if len(in.DefaultParams) != 0 {
+		const prefix string = ",\"default_params\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('{')
+			v20First := true
+			for v20Name, v20Value := range in.DefaultParams {
+				if v20First {
+					v20First = false
+				} else {
+					out.RawByte(',')
+				}
+				out.String(string(v20Name))
+				out.RawByte(':')
+				out.String(string(v20Value))
+			}
+			out.RawByte('}')
+		}
+	}
+	if in.Prefix {
+		const prefix string = ",\"prefix\":"
+		out.RawString(prefix)
+		out.Bool(bool(in.Prefix))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Request) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers7(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Request) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers7(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Request) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers7(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Request) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers7(l, v)
+}
+func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers8(in *jlexer.Lexer, out *Problem) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -31,11 +987,47 @@ func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers(in *j
 		key := in.UnsafeFieldName(false)
 		in.WantColon()
 		switch key {
-		case "result":
+		case "type":
 			if in.IsNull() {
 				in.Skip()
 			} else {
-				out.Result = string(in.String())
+				out.Type = string(in.String())
+			}
+		case "title":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Title = string(in.String())
+			}
+		case "status":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Status = int(in.Int())
+			}
+		case "detail":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Detail = string(in.String())
+			}
+		case "instance":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Instance = string(in.String())
+			}
+		case "code":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Code = string(in.String())
+			}
+		case "request_id":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.RequestID = string(in.String())
 			}
 		default:
 			in.SkipRecursive()
@@ -47,42 +1039,72 @@ func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers(in *j
 		in.Consumed()
 	}
 }
-func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers(out *jwriter.Writer, in Response) {
+func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers8(out *jwriter.Writer, in Problem) {
 	out.RawByte('{')
 	first := true
 	_ = first
 	{
-		const prefix string = ",\"result\":"
+		const prefix string = ",\"type\":"
 		out.RawString(prefix[1:])
-		out.String(string(in.Result))
+		out.String(string(in.Type))
+	}
+	{
+		const prefix string = ",\"title\":"
+		out.RawString(prefix)
+		out.String(string(in.Title))
+	}
+	{
+		const prefix string = ",\"status\":"
+		out.RawString(prefix)
+		out.Int(int(in.Status))
+	}
+	if in.Detail != "" {
+		const prefix string = ",\"detail\":"
+		out.RawString(prefix)
+		out.String(string(in.Detail))
+	}
+	if in.Instance != "" {
+		const prefix string = ",\"instance\":"
+		out.RawString(prefix)
+		out.String(string(in.Instance))
+	}
+	{
+		const prefix string = ",\"code\":"
+		out.RawString(prefix)
+		out.String(string(in.Code))
+	}
+	if in.RequestID != "" {
+		const prefix string = ",\"request_id\":"
+		out.RawString(prefix)
+		out.String(string(in.RequestID))
 	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
-func (v Response) MarshalJSON() ([]byte, error) {
+func (v Problem) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers(&w, v)
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers8(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
-func (v Response) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers(w, v)
+func (v Problem) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers8(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
-func (v *Response) UnmarshalJSON(data []byte) error {
+func (v *Problem) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers(&r, v)
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers8(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
-func (v *Response) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers(l, v)
+func (v *Problem) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers8(l, v)
 }
-func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers1(in *jlexer.Lexer, out *Request) {
+func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers9(in *jlexer.Lexer, out *Link) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -96,11 +1118,47 @@ func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers1(in *
 		key := in.UnsafeFieldName(false)
 		in.WantColon()
 		switch key {
-		case "url":
+		case "id":
 			if in.IsNull() {
 				in.Skip()
 			} else {
-				out.URL = string(in.String())
+				out.ID = string(in.String())
+			}
+		case "short_url":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.ShortURL = string(in.String())
+			}
+		case "original_url":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.OriginalURL = string(in.String())
+			}
+		case "created_at":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				if data := in.Raw(); in.Ok() {
+					in.AddError((out.CreatedAt).UnmarshalJSON(data))
+				}
+			}
+		case "expires_at":
+			if in.IsNull() {
+				in.Skip()
+				out.ExpiresAt = nil
+			} else {
+				if out.ExpiresAt == nil {
+					out.ExpiresAt = new(time.Time)
+				}
+				if in.IsNull() {
+					in.Skip()
+				} else {
+					if data := in.Raw(); in.Ok() {
+						in.AddError((*out.ExpiresAt).UnmarshalJSON(data))
+					}
+				}
 			}
 		default:
 			in.SkipRecursive()
@@ -112,42 +1170,66 @@ func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers1(in *
 		in.Consumed()
 	}
 }
-func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers1(out *jwriter.Writer, in Request) {
+func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers9(out *jwriter.Writer, in Link) {
 	out.RawByte('{')
 	first := true
 	_ = first
 	{
-		const prefix string = ",\"url\":"
+		const prefix string = ",\"id\":"
 		out.RawString(prefix[1:])
-		out.String(string(in.URL))
+		out.String(string(in.ID))
+	}
+	{
+		const prefix string = ",\"short_url\":"
+		out.RawString(prefix)
+		out.String(string(in.ShortURL))
+	}
+	{
+		const prefix string = ",\"original_url\":"
+		out.RawString(prefix)
+		out.String(string(in.OriginalURL))
+	}
+	{
+		const prefix string = ",\"created_at\":"
+		out.RawString(prefix)
+		out.Raw((in.CreatedAt).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"expires_at\":"
+		out.RawString(prefix)
+		if in.ExpiresAt == nil {
+			out.RawString("null")
+		} else {
+			out.Raw((*in.ExpiresAt).MarshalJSON())
+		}
 	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
-func (v Request) MarshalJSON() ([]byte, error) {
+func (v Link) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers1(&w, v)
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers9(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
-func (v Request) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers1(w, v)
+func (v Link) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers9(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
-func (v *Request) UnmarshalJSON(data []byte) error {
+func (v *Link) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers1(&r, v)
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers9(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
-func (v *Request) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers1(l, v)
+func (v *Link) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers9(l, v)
 }
-func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers2(in *jlexer.Lexer, out *BatchResponseItemSlice) {
+func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers10(in *jlexer.Lexer, out *BatchResponseItemSlice) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		in.Skip()
@@ -164,13 +1246,13 @@ func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers2(in *
 			*out = (*out)[:0]
 		}
 		for !in.IsDelim(']') {
-			var v1 BatchResponseItem
+			var v21 BatchResponseItem
 			if in.IsNull() {
 				in.Skip()
 			} else {
-				(v1).UnmarshalEasyJSON(in)
+				(v21).UnmarshalEasyJSON(in)
 			}
-			*out = append(*out, v1)
+			*out = append(*out, v21)
 			in.WantComma()
 		}
 		in.Delim(']')
@@ -179,16 +1261,16 @@ func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers2(in *
 		in.Consumed()
 	}
 }
-func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers2(out *jwriter.Writer, in BatchResponseItemSlice) {
+func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers10(out *jwriter.Writer, in BatchResponseItemSlice) {
 	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
 		out.RawString("null")
 	} else {
 		out.RawByte('[')
-		for v2, v3 := range in {
-			if v2 > 0 {
+		for v22, v23 := range in {
+			if v22 > 0 {
 				out.RawByte(',')
 			}
-			(v3).MarshalEasyJSON(out)
+			(v23).MarshalEasyJSON(out)
 		}
 		out.RawByte(']')
 	}
@@ -197,27 +1279,27 @@ func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers2(out
 // MarshalJSON supports json.Marshaler interface
 func (v BatchResponseItemSlice) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers2(&w, v)
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers10(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v BatchResponseItemSlice) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers2(w, v)
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers10(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *BatchResponseItemSlice) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers2(&r, v)
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers10(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *BatchResponseItemSlice) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers2(l, v)
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers10(l, v)
 }
-func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers3(in *jlexer.Lexer, out *BatchResponseItem) {
+func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers11(in *jlexer.Lexer, out *BatchResponseItem) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -253,7 +1335,7 @@ func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers3(in *
 		in.Consumed()
 	}
 }
-func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers3(out *jwriter.Writer, in BatchResponseItem) {
+func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers11(out *jwriter.Writer, in BatchResponseItem) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -273,27 +1355,27 @@ func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers3(out
 // MarshalJSON supports json.Marshaler interface
 func (v BatchResponseItem) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers3(&w, v)
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers11(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v BatchResponseItem) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers3(w, v)
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers11(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *BatchResponseItem) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers3(&r, v)
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers11(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *BatchResponseItem) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers3(l, v)
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers11(l, v)
 }
-func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers4(in *jlexer.Lexer, out *BatchRequestItemSlice) {
+func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers12(in *jlexer.Lexer, out *BatchRequestItemSlice) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		in.Skip()
@@ -302,7 +1384,7 @@ func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers4(in *
 		in.Delim('[')
 		if *out == nil {
 			if !in.IsDelim(']') {
-				*out = make(BatchRequestItemSlice, 0, 2)
+				*out = make(BatchRequestItemSlice, 0, 0)
 			} else {
 				*out = BatchRequestItemSlice{}
 			}
@@ -310,13 +1392,13 @@ func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers4(in *
 			*out = (*out)[:0]
 		}
 		for !in.IsDelim(']') {
-			var v4 BatchRequestItem
+			var v24 BatchRequestItem
 			if in.IsNull() {
 				in.Skip()
 			} else {
-				(v4).UnmarshalEasyJSON(in)
+				(v24).UnmarshalEasyJSON(in)
 			}
-			*out = append(*out, v4)
+			*out = append(*out, v24)
 			in.WantComma()
 		}
 		in.Delim(']')
@@ -325,16 +1407,16 @@ func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers4(in *
 		in.Consumed()
 	}
 }
-func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers4(out *jwriter.Writer, in BatchRequestItemSlice) {
+func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers12(out *jwriter.Writer, in BatchRequestItemSlice) {
 	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
 		out.RawString("null")
 	} else {
 		out.RawByte('[')
-		for v5, v6 := range in {
-			if v5 > 0 {
+		for v25, v26 := range in {
+			if v25 > 0 {
 				out.RawByte(',')
 			}
-			(v6).MarshalEasyJSON(out)
+			(v26).MarshalEasyJSON(out)
 		}
 		out.RawByte(']')
 	}
@@ -343,27 +1425,27 @@ func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers4(out
 // MarshalJSON supports json.Marshaler interface
 func (v BatchRequestItemSlice) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers4(&w, v)
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers12(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v BatchRequestItemSlice) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers4(w, v)
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers12(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *BatchRequestItemSlice) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers4(&r, v)
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers12(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *BatchRequestItemSlice) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers4(l, v)
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers12(l, v)
 }
-func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers5(in *jlexer.Lexer, out *BatchRequestItem) {
+func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers13(in *jlexer.Lexer, out *BatchRequestItem) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -389,6 +1471,121 @@ func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers5(in *
 			} else {
 				out.OriginalURL = string(in.String())
 			}
+		case "variants":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				(out.Variants).UnmarshalEasyJSON(in)
+			}
+		case "dedupe":
+			if in.IsNull() {
+				in.Skip()
+				out.Dedupe = nil
+			} else {
+				if out.Dedupe == nil {
+					out.Dedupe = new(bool)
+				}
+				if in.IsNull() {
+					in.Skip()
+				} else {
+					*out.Dedupe = bool(in.Bool())
+				}
+			}
+		case "scope":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Scope = string(in.String())
+			}
+		case "redirect_code":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.RedirectCode = int(in.Int())
+			}
+		case "cache_max_age":
+			if in.IsNull() {
+				in.Skip()
+				out.CacheMaxAge = nil
+			} else {
+				if out.CacheMaxAge == nil {
+					out.CacheMaxAge = new(int)
+				}
+				if in.IsNull() {
+					in.Skip()
+				} else {
+					*out.CacheMaxAge = int(in.Int())
+				}
+			}
+		case "referrer_policy":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.ReferrerPolicy = string(in.String())
+			}
+		case "query_mode":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.QueryMode = string(in.String())
+			}
+		case "query_allowlist":
+			if in.IsNull() {
+				in.Skip()
+				out.QueryAllowlist = nil
+			} else {
+				in.Delim('[')
+				if out.QueryAllowlist == nil {
+					if !in.IsDelim(']') {
+						out.QueryAllowlist = make([]string, 0, 4)
+					} else {
+						out.QueryAllowlist = []string{}
+					}
+				} else {
+					out.QueryAllowlist = (out.QueryAllowlist)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v27 string
+					if in.IsNull() {
+						in.Skip()
+					} else {
+						v27 = string(in.String())
+					}
+					out.QueryAllowlist = append(out.QueryAllowlist, v27)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "default_params":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				if !in.IsDelim('}') {
+					out.DefaultParams = make(map[string]string)
+				} else {
+					out.DefaultParams = nil
+				}
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v28 string
+					if in.IsNull() {
+						in.Skip()
+					} else {
+						v28 = string(in.String())
+					}
+					(out.DefaultParams)[key] = v28
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		case "prefix":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				out.Prefix = bool(in.Bool())
+			}
 		default:
 			in.SkipRecursive()
 		}
@@ -399,7 +1596,7 @@ func easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers5(in *
 		in.Consumed()
 	}
 }
-func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers5(out *jwriter.Writer, in BatchRequestItem) {
+func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers13(out *jwriter.Writer, in BatchRequestItem) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -413,29 +1610,102 @@ func easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers5(out
 		out.RawString(prefix)
 		out.String(string(in.OriginalURL))
 	}
+	if len(in.Variants) != 0 {
+		const prefix string = ",\"variants\":"
+		out.RawString(prefix)
+		(in.Variants).MarshalEasyJSON(out)
+	}
+	if in.Dedupe != nil {
+		const prefix string = ",\"dedupe\":"
+		out.RawString(prefix)
+		out.Bool(bool(*in.Dedupe))
+	}
+	if in.Scope != "" {
+		const prefix string = ",\"scope\":"
+		out.RawString(prefix)
+		out.String(string(in.Scope))
+	}
+	if in.RedirectCode != 0 {
+		const prefix string = ",\"redirect_code\":"
+		out.RawString(prefix)
+		out.Int(int(in.RedirectCode))
+	}
+	if in.CacheMaxAge != nil {
+		const prefix string = ",\"cache_max_age\":"
+		out.RawString(prefix)
+		out.Int(int(*in.CacheMaxAge))
+	}
+	if in.ReferrerPolicy != "" {
+		const prefix string = ",\"referrer_policy\":"
+		out.RawString(prefix)
+		out.String(string(in.ReferrerPolicy))
+	}
+	if in.QueryMode != "" {
+		const prefix string = ",\"query_mode\":"
+		out.RawString(prefix)
+		out.String(string(in.QueryMode))
+	}
+	if len(in.QueryAllowlist) != 0 {
+		const prefix string = ",\"query_allowlist\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('[')
+			for v29, v30 := range in.QueryAllowlist {
+				if v29 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v30))
+			}
+			out.RawByte(']')
+		}
+	}
+	if len(in.DefaultParams) != 0 {
+		const prefix string = ",\"default_params\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('{')
+			v31First := true
+			for v31Name, v31Value := range in.DefaultParams {
+				if v31First {
+					v31First = false
+				} else {
+					out.RawByte(',')
+				}
+				out.String(string(v31Name))
+				out.RawByte(':')
+				out.String(string(v31Value))
+			}
+			out.RawByte('}')
+		}
+	}
+	if in.Prefix {
+		const prefix string = ",\"prefix\":"
+		out.RawString(prefix)
+		out.Bool(bool(in.Prefix))
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
 func (v BatchRequestItem) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers5(&w, v)
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers13(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v BatchRequestItem) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers5(w, v)
+	easyjsonA970e379EncodeGithubComOleg2210GoshortenerInternalSerializers13(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *BatchRequestItem) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers5(&r, v)
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers13(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *BatchRequestItem) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers5(l, v)
+	easyjsonA970e379DecodeGithubComOleg2210GoshortenerInternalSerializers13(l, v)
 }
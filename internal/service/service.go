@@ -4,10 +4,16 @@ import (
 	"context"
 	"errors"
 	"math/rand"
+	randv2 "math/rand/v2"
 	"time"
 
 	"github.com/Oleg2210/goshortener/internal/entities"
+	"github.com/Oleg2210/goshortener/internal/metrics"
 	"github.com/Oleg2210/goshortener/internal/repository"
+	"github.com/Oleg2210/goshortener/internal/rules"
+	"github.com/Oleg2210/goshortener/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var ErrOutOfCombinations = errors.New("possible combinations are running out")
@@ -38,6 +44,16 @@ func NewShortenerService(
 	}
 }
 
+func validateRecord(record entities.URLRecord) error {
+	if err := record.Redirect.Validate(); err != nil {
+		return err
+	}
+	if err := entities.ValidateVariants(record.Variants); err != nil {
+		return err
+	}
+	return entities.ValidateScope(record.Scope)
+}
+
 func (service *ShortenerService) generateRandomID(letters string, size int) string {
 	randomText := make([]byte, size)
 	for i := range randomText {
@@ -51,44 +67,198 @@ func (service *ShortenerService) Shorten(
 	ctx context.Context,
 	url string,
 ) (string, error) {
+	return service.ShortenWithPolicy(ctx, url, entities.RedirectPolicy{})
+}
+
+// ShortenWithPolicy сокращает url и сохраняет вместе с ним параметры редиректа.
+// Повторный url в общей области возвращает прежний id вместе с ErrURLExists.
+func (service *ShortenerService) ShortenWithPolicy(
+	ctx context.Context,
+	url string,
+	policy entities.RedirectPolicy,
+) (string, error) {
+	return service.ShortenRecord(ctx, entities.URLRecord{
+		OriginalURL: url,
+		Redirect:    policy,
+		Dedupe:      true,
+	})
+}
+
+// ShortenRecord сокращает record.OriginalURL и сохраняет остальные поля записи.
+// Short подбирается сервисом, заданное значение игнорируется. При record.Dedupe
+// уже сокращенный в области record.Scope адрес возвращается вместе с ErrURLExists,
+// остальные поля новой записи при этом не применяются.
+func (service *ShortenerService) ShortenRecord(
+	ctx context.Context,
+	record entities.URLRecord,
+) (string, error) {
+	if err := validateRecord(record); err != nil {
+		return "", err
+	}
+
+	ctx, span := tracing.Start(ctx, "ShortenerService.Shorten")
+
+	attempts := 0
 	for i := service.minLength; i < service.maxLength; i++ {
+		attempts++
 		id := service.generateRandomID(
 			service.letters,
 			i,
 		)
-		short, err := service.repo.Save(
-			ctx,
-			id,
-			url,
-		)
+		record.Short = id
+		short, err := service.repo.Save(ctx, record)
+
+		if err != nil && !errors.Is(err, repository.ErrAlreadyExists) {
+			metrics.ShortenAttempts.Observe(float64(attempts))
+			tracing.End(span, err)
+			return "", err
+		}
 
 		if err == nil {
+			metrics.ShortenAttempts.Observe(float64(attempts))
+			span.SetAttributes(attribute.Int("shorten.attempts", attempts))
+			tracing.End(span, nil)
 			if short != id {
 				return short, ErrURLExists
 			}
 			return id, nil
 		}
+
+		metrics.ShortenRetries.Inc()
 	}
 
+	metrics.ShortenAttempts.Observe(float64(attempts))
+	metrics.OutOfCombinations.Inc()
+	span.SetAttributes(attribute.Int("shorten.attempts", attempts))
+	tracing.End(span, ErrOutOfCombinations)
 	return "", ErrOutOfCombinations
 }
 
+// BatchShorten сохраняет записи пачкой и возвращает short каждой. Как и в
+// Shorten, для адреса, уже сокращенного в области записи с Dedupe, возвращается
+// существующий short, а новая запись не создается.
 func (service *ShortenerService) BatchShorten(
 	ctx context.Context,
 	records []entities.URLRecord,
-) error {
-	return service.repo.BatchSave(ctx, records)
+) ([]string, error) {
+	for _, r := range records {
+		if err := validateRecord(r); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, span := tracing.Start(ctx, "ShortenerService.BatchShorten", attribute.Int("batch.size", len(records)))
+
+	shorts, err := service.repo.BatchSave(ctx, records)
+	if errors.Is(err, repository.ErrOriginalExists) {
+		err = ErrURLExists
+	}
+	tracing.End(span, err)
+	return shorts, err
 }
 
+// GetURL возвращает адрес назначения. Для A/B-ссылки выбирается вариант по весам
+// и засчитывается клик.
 func (service *ShortenerService) GetURL(ctx context.Context, id string) (string, error) {
-	url, exists := service.repo.Get(ctx, id)
-	if !exists {
-		return "", ErrIDDoesNotExists
+	record, err := service.GetRecord(ctx, id)
+	if err != nil {
+		return "", err
 	}
 
-	return url, nil
+	variant, ok := ChooseVariant(record.Variants, "")
+	if !ok {
+		return record.OriginalURL, nil
+	}
+
+	service.CountClick(ctx, id, variant.Name)
+	return variant.URL, nil
+}
+
+// ChooseVariant возвращает вариант с именем sticky, если он есть, иначе
+// случайный с вероятностью, пропорциональной весу.
+func ChooseVariant(variants []entities.Variant, sticky string) (entities.Variant, bool) {
+	if len(variants) == 0 {
+		return entities.Variant{}, false
+	}
+
+	total := 0
+	for _, v := range variants {
+		if sticky != "" && v.Name == sticky {
+			return v, true
+		}
+		total += v.Weight
+	}
+	if total <= 0 {
+		return variants[0], true
+	}
+
+	n := randv2.IntN(total)
+	for _, v := range variants {
+		if n < v.Weight {
+			return v, true
+		}
+		n -= v.Weight
+	}
+	return variants[len(variants)-1], true
+}
+
+// CountClick засчитывает переход на вариант. Ошибка счетчика не должна
+// ломать редирект, поэтому она только отмечается в трассе.
+func (service *ShortenerService) CountClick(ctx context.Context, id string, variant string) {
+	if err := service.repo.IncrementClicks(ctx, id, variant); err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+}
+
+// SetVariants заменяет варианты A/B-ссылки.
+func (service *ShortenerService) SetVariants(ctx context.Context, id string, variants []entities.Variant) error {
+	if err := entities.ValidateVariants(variants); err != nil {
+		return err
+	}
+
+	ctx, span := tracing.Start(ctx, "ShortenerService.SetVariants", attribute.String("short.id", id))
+
+	err := service.repo.SetVariants(ctx, id, variants)
+	if errors.Is(err, repository.ErrNotFound) {
+		err = ErrIDDoesNotExists
+	}
+	tracing.End(span, err)
+	return err
+}
+
+// GetRecord возвращает запись ссылки. Недоступность хранилища не выдается
+// за отсутствие ссылки: вернется ошибка хранилища, а не ErrIDDoesNotExists.
+func (service *ShortenerService) GetRecord(ctx context.Context, id string) (entities.URLRecord, error) {
+	ctx, span := tracing.Start(ctx, "ShortenerService.GetRecord", attribute.String("short.id", id))
+
+	record, err := service.repo.GetRecord(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		span.End()
+		return entities.URLRecord{}, ErrIDDoesNotExists
+	}
+	tracing.End(span, err)
+	return record, err
+}
+
+// SetRules проверяет и сохраняет правила ссылки, заменяя прежние.
+func (service *ShortenerService) SetRules(ctx context.Context, id string, list []entities.Rule) error {
+	if err := rules.Validate(list); err != nil {
+		return err
+	}
+
+	ctx, span := tracing.Start(ctx, "ShortenerService.SetRules", attribute.String("short.id", id))
+
+	err := service.repo.SetRules(ctx, id, list)
+	if errors.Is(err, repository.ErrNotFound) {
+		err = ErrIDDoesNotExists
+	}
+	tracing.End(span, err)
+	return err
 }
 
 func (service *ShortenerService) Ping(ctx context.Context) bool {
+	ctx, span := tracing.Start(ctx, "ShortenerService.Ping")
+	defer span.End()
+
 	return service.repo.Ping(ctx)
 }
@@ -0,0 +1,65 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Oleg2210/goshortener/internal/config"
+	"github.com/Oleg2210/goshortener/internal/handler"
+	"github.com/Oleg2210/goshortener/internal/repository"
+	"github.com/Oleg2210/goshortener/internal/service"
+	"github.com/Oleg2210/goshortener/internal/tracing"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+func TestTraceparentPropagatesToRepository(t *testing.T) {
+	_, err := tracing.Setup(context.Background(), tracing.Options{})
+	require.NoError(t, err)
+
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown := tracing.SetupWithExporter(exporter, 1)
+	defer shutdown(context.Background())
+
+	cfg := config.Default()
+	app := handler.App{
+		ShortenerService: service.NewShortenerService(
+			repository.NewInstrumentedRepository(repository.NewMemoryRepository(), "memory"),
+			cfg.MinLength,
+			cfg.MaxLength,
+		),
+		Logger:  zap.NewNop(),
+		BaseURL: cfg.BaseURL,
+	}
+
+	router := chi.NewRouter()
+	router.Use(tracing.Middleware)
+	router.Post("/", app.HandlePost)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://yandex.kz"))
+	request.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	provider, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+	require.True(t, ok)
+	require.NoError(t, provider.ForceFlush(context.Background()))
+
+	names := map[string]bool{}
+	for _, span := range exporter.GetSpans() {
+		assert.Equal(t, traceID, span.SpanContext.TraceID().String())
+		names[span.Name] = true
+	}
+
+	assert.True(t, names["POST /"])
+	assert.True(t, names["ShortenerService.Shorten"])
+	assert.True(t, names["repository.save"])
+}
@@ -0,0 +1,4 @@
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS redirect_code,
+    DROP COLUMN IF EXISTS cache_max_age,
+    DROP COLUMN IF EXISTS referrer_policy;
@@ -0,0 +1,4 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS redirect_code integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS cache_max_age integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS referrer_policy text NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS query_mode,
+    DROP COLUMN IF EXISTS query_allowlist,
+    DROP COLUMN IF EXISTS default_params;
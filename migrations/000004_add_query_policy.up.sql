@@ -0,0 +1,4 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS query_mode text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS query_allowlist jsonb NOT NULL DEFAULT '[]',
+    ADD COLUMN IF NOT EXISTS default_params jsonb NOT NULL DEFAULT '{}';
@@ -0,0 +1 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS prefix boolean NOT NULL DEFAULT false;
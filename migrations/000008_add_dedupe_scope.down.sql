@@ -0,0 +1,15 @@
+-- без областей и флага дубли original снова запрещены. Удалять ссылки
+-- пользователей откат не станет: если дубли есть, их нужно убрать вручную.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM urls GROUP BY original HAVING count(*) > 1) THEN
+        RAISE EXCEPTION 'urls has duplicate originals, remove them before rolling back 000008_add_dedupe_scope';
+    END IF;
+END $$;
+
+DROP INDEX IF EXISTS idx_urls_original;
+
+ALTER TABLE urls DROP COLUMN IF EXISTS dedupe;
+ALTER TABLE urls DROP COLUMN IF EXISTS scope;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_original ON urls(original);
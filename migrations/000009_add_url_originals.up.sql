@@ -0,0 +1,12 @@
+-- глобальный индекс original→short шардированного хранилища: живет в базе
+-- DATABASE_DSN и не дает разным шардам сохранить один адрес под разными id
+CREATE TABLE IF NOT EXISTS url_originals (
+    scope text NOT NULL,
+    original text NOT NULL,
+    short text NOT NULL,
+    PRIMARY KEY (scope, original)
+);
+
+INSERT INTO url_originals(scope, original, short)
+SELECT scope, original, short FROM urls WHERE dedupe
+ON CONFLICT DO NOTHING;
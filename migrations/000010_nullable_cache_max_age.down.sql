@@ -0,0 +1,4 @@
+-- явный 0 после отката снова означает глобальную настройку
+UPDATE urls SET cache_max_age = 0 WHERE cache_max_age IS NULL;
+ALTER TABLE urls ALTER COLUMN cache_max_age SET DEFAULT 0;
+ALTER TABLE urls ALTER COLUMN cache_max_age SET NOT NULL;
@@ -0,0 +1,5 @@
+-- NULL — время кеширования не задано и берется из глобальной настройки, явный 0
+-- отключает заголовки кеширования у ссылки. До этой миграции 0 означал NULL.
+ALTER TABLE urls ALTER COLUMN cache_max_age DROP NOT NULL;
+ALTER TABLE urls ALTER COLUMN cache_max_age DROP DEFAULT;
+UPDATE urls SET cache_max_age = NULL WHERE cache_max_age = 0;
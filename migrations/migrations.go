@@ -0,0 +1,15 @@
+// Package migrations встраивает SQL-миграции схемы в бинарник, чтобы сервис
+// не зависел от рабочего каталога.
+package migrations
+
+import "embed"
+
+// FS — миграции Postgres.
+//
+//go:embed *.sql
+var FS embed.FS
+
+// SQLite — те же миграции на диалекте SQLite, в каталоге sqlite.
+//
+//go:embed sqlite/*.sql
+var SQLite embed.FS
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS urls (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    short text NOT NULL UNIQUE,
+    original text NOT NULL,
+    created_at timestamp NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE UNIQUE INDEX idx_urls_short ON urls(short);
@@ -0,0 +1 @@
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_original ON urls(original);
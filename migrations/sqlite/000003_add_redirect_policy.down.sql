@@ -0,0 +1,3 @@
+ALTER TABLE urls DROP COLUMN redirect_code;
+ALTER TABLE urls DROP COLUMN cache_max_age;
+ALTER TABLE urls DROP COLUMN referrer_policy;
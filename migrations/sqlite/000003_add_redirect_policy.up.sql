@@ -0,0 +1,3 @@
+ALTER TABLE urls ADD COLUMN redirect_code integer NOT NULL DEFAULT 0;
+ALTER TABLE urls ADD COLUMN cache_max_age integer NOT NULL DEFAULT 0;
+ALTER TABLE urls ADD COLUMN referrer_policy text NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE urls DROP COLUMN query_mode;
+ALTER TABLE urls DROP COLUMN query_allowlist;
+ALTER TABLE urls DROP COLUMN default_params;
@@ -0,0 +1,3 @@
+ALTER TABLE urls ADD COLUMN query_mode text NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN query_allowlist text NOT NULL DEFAULT '[]';
+ALTER TABLE urls ADD COLUMN default_params text NOT NULL DEFAULT '{}';
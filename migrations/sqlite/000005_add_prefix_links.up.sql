@@ -0,0 +1 @@
+ALTER TABLE urls ADD COLUMN prefix boolean NOT NULL DEFAULT false;
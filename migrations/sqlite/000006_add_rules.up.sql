@@ -0,0 +1 @@
+ALTER TABLE urls ADD COLUMN rules text NOT NULL DEFAULT '[]';
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS url_variants (
+    short text NOT NULL REFERENCES urls(short) ON DELETE CASCADE,
+    position integer NOT NULL,
+    name text NOT NULL,
+    url text NOT NULL,
+    weight integer NOT NULL CHECK (weight > 0),
+    clicks integer NOT NULL DEFAULT 0,
+    PRIMARY KEY (short, name)
+);
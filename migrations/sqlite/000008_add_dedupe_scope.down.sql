@@ -0,0 +1,16 @@
+-- без областей и флага дубли original снова запрещены. Удалять ссылки
+-- пользователей откат не станет: если дубли есть, их нужно убрать вручную.
+-- RAISE в SQLite доступен только в триггерах, поэтому ошибку дает CHECK.
+CREATE TEMP TABLE rollback_guard (
+    duplicates integer CONSTRAINT urls_has_duplicate_originals_remove_them_before_rollback CHECK (duplicates = 0)
+);
+INSERT INTO rollback_guard
+SELECT count(*) FROM (SELECT original FROM urls GROUP BY original HAVING count(*) > 1);
+DROP TABLE rollback_guard;
+
+DROP INDEX IF EXISTS idx_urls_original;
+
+ALTER TABLE urls DROP COLUMN dedupe;
+ALTER TABLE urls DROP COLUMN scope;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_original ON urls(original);
@@ -0,0 +1,5 @@
+ALTER TABLE urls ADD COLUMN scope text NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN dedupe boolean NOT NULL DEFAULT true;
+
+DROP INDEX IF EXISTS idx_urls_original;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_original ON urls(scope, original) WHERE dedupe;
@@ -0,0 +1,8 @@
+-- таблица нужна только шардированному хранилищу на Postgres; создается,
+-- чтобы схемы и версии миграций у обеих баз совпадали
+CREATE TABLE IF NOT EXISTS url_originals (
+    scope text NOT NULL,
+    original text NOT NULL,
+    short text NOT NULL,
+    PRIMARY KEY (scope, original)
+);
@@ -0,0 +1,5 @@
+-- явный 0 после отката снова означает глобальную настройку
+ALTER TABLE urls ADD COLUMN cache_max_age_not_null integer NOT NULL DEFAULT 0;
+UPDATE urls SET cache_max_age_not_null = coalesce(cache_max_age, 0);
+ALTER TABLE urls DROP COLUMN cache_max_age;
+ALTER TABLE urls RENAME COLUMN cache_max_age_not_null TO cache_max_age;
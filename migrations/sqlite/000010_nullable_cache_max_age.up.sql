@@ -0,0 +1,7 @@
+-- NULL — время кеширования не задано и берется из глобальной настройки, явный 0
+-- отключает заголовки кеширования у ссылки. До этой миграции 0 означал NULL.
+-- ограничение NOT NULL в SQLite не снимается, поэтому колонка пересоздается.
+ALTER TABLE urls ADD COLUMN cache_max_age_nullable integer;
+UPDATE urls SET cache_max_age_nullable = NULLIF(cache_max_age, 0);
+ALTER TABLE urls DROP COLUMN cache_max_age;
+ALTER TABLE urls RENAME COLUMN cache_max_age_nullable TO cache_max_age;
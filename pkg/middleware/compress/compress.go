@@ -0,0 +1,243 @@
+package compres
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errUnsupportedEncoding = errors.New("unsupported content encoding")
+
+type Options struct {
+	// ответы меньше MinSize байт отдаются без сжатия
+	MinSize int
+	// типы содержимого, которые имеет смысл сжимать
+	ContentTypes []string
+	// предельный размер распакованного тела запроса, защита от zip-бомб
+	MaxDecompressedSize int64
+}
+
+func DefaultOptions() Options {
+	return Options{
+		MinSize: 1024,
+		ContentTypes: []string{
+			"application/json",
+			"application/problem+json",
+			"application/javascript",
+			"text/html",
+			"text/plain",
+			"text/css",
+			"text/xml",
+		},
+		MaxDecompressedSize: 10 << 20,
+	}
+}
+
+type bodyReader struct {
+	io.Reader
+	decoder io.Closer
+	body    io.Closer
+}
+
+func (br *bodyReader) Close() error {
+	_ = br.decoder.Close()
+	return br.body.Close()
+}
+
+// compressWriter откладывает решение о сжатии до первой записи тела,
+// когда обработчик уже выставил Content-Type, и копит MinSize байт,
+// чтобы не сжимать короткие ответы.
+type compressWriter struct {
+	http.ResponseWriter
+	opts     *Options
+	encoding string
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     encoder
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided || cw.status != 0 {
+		return
+	}
+	cw.status = code
+
+	// у таких ответов нет тела, ждать его бессмысленно
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+		cw.passthrough()
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.decided {
+		if !cw.compressible() {
+			if err := cw.flushBuffered(); err != nil {
+				return 0, err
+			}
+		} else {
+			cw.buf = append(cw.buf, p...)
+			if len(cw.buf) >= cw.opts.MinSize {
+				if err := cw.startCompression(); err != nil {
+					return 0, err
+				}
+			}
+			return len(p), nil
+		}
+	}
+
+	if cw.enc != nil {
+		return cw.enc.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.compressible() && len(cw.buf) > 0 {
+			cw.startCompression()
+		} else {
+			cw.flushBuffered()
+		}
+	}
+
+	if f, ok := cw.enc.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *compressWriter) compressible() bool {
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	for _, ct := range cw.opts.ContentTypes {
+		if strings.EqualFold(mediaType, ct) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cw *compressWriter) writeHeader() {
+	if cw.status != 0 {
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+}
+
+func (cw *compressWriter) passthrough() {
+	cw.decided = true
+	cw.writeHeader()
+}
+
+func (cw *compressWriter) flushBuffered() error {
+	cw.passthrough()
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	_, err := cw.ResponseWriter.Write(cw.buf)
+	cw.buf = nil
+	return err
+}
+
+func (cw *compressWriter) startCompression() error {
+	cw.decided = true
+
+	h := cw.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Encoding", cw.encoding)
+	cw.writeHeader()
+
+	cw.enc = acquireEncoder(cw.encoding, cw.ResponseWriter)
+	_, err := cw.enc.Write(cw.buf)
+	cw.buf = nil
+	return err
+}
+
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if len(cw.buf) > 0 {
+			cw.Header().Set("Content-Length", strconv.Itoa(len(cw.buf)))
+		}
+		return cw.flushBuffered()
+	}
+
+	if cw.enc == nil {
+		return nil
+	}
+
+	err := cw.enc.Close()
+	releaseEncoder(cw.encoding, cw.enc)
+	cw.enc = nil
+	return err
+}
+
+func decompressRequest(w http.ResponseWriter, r *http.Request, maxSize int64) int {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" {
+		return 0
+	}
+
+	decoder, err := newDecoder(encoding, r.Body)
+	if errors.Is(err, errUnsupportedEncoding) {
+		return http.StatusUnsupportedMediaType
+	}
+	if err != nil {
+		return http.StatusBadRequest
+	}
+
+	var reader io.Reader = decoder
+	if maxSize > 0 {
+		// MaxBytesReader вернет *http.MaxBytesError, по которому обработчик ответит 413
+		reader = http.MaxBytesReader(w, decoder, maxSize)
+	}
+
+	r.Body = &bodyReader{Reader: reader, decoder: decoder, body: r.Body}
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	r.ContentLength = -1
+
+	return 0
+}
+
+// Middleware распаковывает тела запросов в gzip, deflate, br и zstd
+// и сжимает ответы в кодировке, выбранной по Accept-Encoding.
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if status := decompressRequest(w, r, opts.MaxDecompressedSize); status != 0 {
+				http.Error(w, "invalid content encoding", status)
+				return
+			}
+
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				opts:           &opts,
+				encoding:       encoding,
+			}
+			defer cw.Close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
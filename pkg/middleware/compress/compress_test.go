@@ -0,0 +1,151 @@
+package compres
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"gzip;q=1.0, br;q=0.5", "gzip"},
+		{"zstd;q=0.8, deflate", "deflate"},
+		{"br;q=0, *;q=0.1", "zstd"},
+		{"identity", ""},
+		{"gzip;q=0", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, negotiateEncoding(tt.header), tt.header)
+	}
+}
+
+func serve(opts Options, contentType string, body string, request *http.Request) *httptest.ResponseRecorder {
+	handler := Middleware(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, body)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestCompressesOnceContentTypeIsKnown(t *testing.T) {
+	body := strings.Repeat(`{"result":"http://localhost:8080/abcde"}`, 50)
+	request := httptest.NewRequest(http.MethodPost, "/api/shorten", nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+
+	recorder := serve(DefaultOptions(), "application/json", body, request)
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+	assert.Contains(t, recorder.Header().Values("Vary"), "Accept-Encoding")
+
+	zr, err := gzip.NewReader(recorder.Body)
+	require.NoError(t, err)
+	got, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(got))
+}
+
+func TestSkipsSmallAndIncompressibleResponses(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Accept-Encoding", "br")
+
+	small := serve(DefaultOptions(), "application/json", `{"result":"x"}`, request)
+	assert.Empty(t, small.Header().Get("Content-Encoding"))
+	assert.Equal(t, "14", small.Header().Get("Content-Length"))
+	assert.Equal(t, `{"result":"x"}`, small.Body.String())
+
+	image := serve(DefaultOptions(), "image/png", strings.Repeat("x", 4096), request)
+	assert.Empty(t, image.Header().Get("Content-Encoding"))
+	assert.Equal(t, http.StatusCreated, image.Code)
+
+	large := serve(DefaultOptions(), "text/plain", strings.Repeat("x", 4096), request)
+	assert.Equal(t, "br", large.Header().Get("Content-Encoding"))
+	got, err := io.ReadAll(brotli.NewReader(large.Body))
+	require.NoError(t, err)
+	assert.Len(t, got, 4096)
+}
+
+func TestDecompressesRequestWithSizeCap(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(bytes.Repeat([]byte("a"), 2048))
+	zw.Close()
+
+	var readErr error
+	var readLen int
+	opts := DefaultOptions()
+	opts.MaxDecompressedSize = 1024
+	handler := Middleware(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		readErr, readLen = err, len(body)
+	}))
+
+	request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(compressed.Bytes()))
+	request.Header.Set("Content-Encoding", "gzip")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	var maxErr *http.MaxBytesError
+	assert.True(t, errors.As(readErr, &maxErr))
+	assert.Equal(t, 1024, readLen)
+
+	request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("x"))
+	request.Header.Set("Content-Encoding", "compress")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusUnsupportedMediaType, recorder.Code)
+}
+
+func TestDeflateIsZlib(t *testing.T) {
+	body := strings.Repeat(`{"url":"https://example.com"}`, 50)
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write([]byte(body))
+	zw.Close()
+
+	var received string
+	handler := Middleware(DefaultOptions())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+		received = string(got)
+
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, received)
+	}))
+
+	request := httptest.NewRequest(http.MethodPost, "/", &compressed)
+	request.Header.Set("Content-Encoding", "deflate")
+	request.Header.Set("Accept-Encoding", "deflate")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, body, received)
+	assert.Equal(t, "deflate", recorder.Header().Get("Content-Encoding"))
+
+	zr, err := zlib.NewReader(recorder.Body)
+	require.NoError(t, err)
+	got, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(got))
+}
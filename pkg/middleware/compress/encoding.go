@@ -0,0 +1,155 @@
+package compres
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	encodingBrotli  = "br"
+	encodingZstd    = "zstd"
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+// порядок предпочтения сервера при одинаковом q
+var serverPreference = []string{encodingBrotli, encodingZstd, encodingGzip, encodingDeflate}
+
+type encoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+type zstdEncoder struct {
+	*zstd.Encoder
+}
+
+func (z zstdEncoder) Reset(w io.Writer) {
+	z.Encoder.Reset(w)
+}
+
+var encoderPools = map[string]*sync.Pool{
+	encodingGzip: {New: func() any {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	}},
+	// в HTTP deflate — это поток zlib (RFC 9110, 8.4.1.2), а не голый DEFLATE
+	encodingDeflate: {New: func() any {
+		w, _ := zlib.NewWriterLevel(nil, zlib.DefaultCompression)
+		return w
+	}},
+	encodingBrotli: {New: func() any {
+		return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+	}},
+	encodingZstd: {New: func() any {
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return zstdEncoder{w}
+	}},
+}
+
+func acquireEncoder(encoding string, w io.Writer) encoder {
+	enc := encoderPools[encoding].Get().(encoder)
+	enc.Reset(w)
+	return enc
+}
+
+func releaseEncoder(encoding string, enc encoder) {
+	encoderPools[encoding].Put(enc)
+}
+
+type acceptedEncoding struct {
+	name string
+	q    float64
+}
+
+func parseAcceptEncoding(header string) []acceptedEncoding {
+	var accepted []acceptedEncoding
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(p), "=")
+			if ok && strings.TrimSpace(key) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		accepted = append(accepted, acceptedEncoding{name: name, q: q})
+	}
+
+	return accepted
+}
+
+// negotiateEncoding выбирает кодировку ответа по Accept-Encoding с учетом q-значений.
+// Пустая строка означает, что ответ нужно отдать без сжатия.
+func negotiateEncoding(header string) string {
+	accepted := parseAcceptEncoding(header)
+	if len(accepted) == 0 {
+		return ""
+	}
+
+	qualities := make(map[string]float64, len(serverPreference))
+	wildcard, hasWildcard := 0.0, false
+	for _, a := range accepted {
+		if a.name == "*" {
+			wildcard, hasWildcard = a.q, true
+			continue
+		}
+		qualities[a.name] = a.q
+	}
+
+	candidates := make([]acceptedEncoding, 0, len(serverPreference))
+	for _, name := range serverPreference {
+		q, ok := qualities[name]
+		if !ok && hasWildcard {
+			q, ok = wildcard, true
+		}
+		if ok && q > 0 {
+			candidates = append(candidates, acceptedEncoding{name: name, q: q})
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	return candidates[0].name
+}
+
+func newDecoder(encoding string, r io.Reader) (io.ReadCloser, error) {
+	switch encoding {
+	case encodingGzip:
+		return gzip.NewReader(r)
+	case encodingDeflate:
+		return zlib.NewReader(r)
+	case encodingBrotli:
+		return io.NopCloser(brotli.NewReader(r)), nil
+	case encodingZstd:
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	default:
+		return nil, errUnsupportedEncoding
+	}
+}
@@ -0,0 +1,19 @@
+package limit
+
+import (
+	"net/http"
+)
+
+// BodyLimit ограничивает размер тела запроса maxBytes байтами. При превышении
+// обработчик получает *http.MaxBytesError при чтении и сам отвечает 413
+// в формате своего маршрута.
+func BodyLimit(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if maxBytes > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
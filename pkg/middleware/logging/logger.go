@@ -0,0 +1,32 @@
+package logging
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewLogger собирает zap.Logger с заданным уровнем и форматом ("json" или "console").
+func NewLogger(level string, format string) (*zap.Logger, error) {
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg zap.Config
+	switch format {
+	case "json":
+		cfg = zap.NewProductionConfig()
+	case "console":
+		cfg = zap.NewDevelopmentConfig()
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+
+	cfg.Level = zap.NewAtomicLevelAt(lvl)
+	// семплирование настраивается отдельно для логов редиректов
+	cfg.Sampling = nil
+
+	return cfg.Build()
+}
@@ -2,16 +2,20 @@ package logging
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 type loggingResponseWriter struct {
 	http.ResponseWriter
 	status int
+	size   int
 }
 
 func (lrw *loggingResponseWriter) WriteHeader(code int) {
@@ -19,7 +23,66 @@ func (lrw *loggingResponseWriter) WriteHeader(code int) {
 	lrw.ResponseWriter.WriteHeader(code)
 }
 
-func LoggingMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
+func (lrw *loggingResponseWriter) Write(p []byte) (int, error) {
+	n, err := lrw.ResponseWriter.Write(p)
+	lrw.size += n
+	return n, err
+}
+
+// capturingReader запоминает не больше limit первых байт тела по мере того,
+// как его читает обработчик, не буферизуя запрос целиком.
+type capturingReader struct {
+	io.ReadCloser
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (cr *capturingReader) Read(p []byte) (int, error) {
+	n, err := cr.ReadCloser.Read(p)
+
+	if rest := cr.limit - cr.buf.Len(); rest > 0 {
+		cr.buf.Write(p[:min(n, rest)])
+		if n > rest {
+			cr.truncated = true
+		}
+	} else if n > 0 {
+		cr.truncated = true
+	}
+
+	return n, err
+}
+
+// TraceFields возвращает trace_id и span_id активного спана из ctx, если он есть.
+func TraceFields(ctx context.Context) []zap.Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+
+	return []zap.Field{
+		zap.String("trace_id", sc.TraceID().String()),
+		zap.String("span_id", sc.SpanID().String()),
+	}
+}
+
+func isRedirect(r *http.Request, status int) bool {
+	return r.Method == http.MethodGet && status >= 300 && status < 400
+}
+
+// LoggingMiddleware пишет строку лога на каждый запрос. Тело запроса логируется
+// в том виде, в каком его читают хэндлеры, поэтому middleware распаковки
+// должен стоять снаружи.
+func LoggingMiddleware(logger *zap.Logger, opts Options) func(http.Handler) http.Handler {
+	redactor := newRedactor(opts.RedactQueryParams, opts.RedactJSONFields)
+
+	redirectLogger := logger
+	if opts.SampleInitial > 0 {
+		redirectLogger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewSamplerWithOptions(core, time.Second, opts.SampleInitial, opts.SampleThereafter)
+		}))
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -29,23 +92,41 @@ func LoggingMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
 				status:         200,
 			}
 
-			var body []byte
-			if r.Method == http.MethodPost {
-				body, _ = io.ReadAll(r.Body)
-				r.Body = io.NopCloser(bytes.NewBuffer(body))
+			var captured *capturingReader
+			if opts.BodyMode != BodyOff && r.Body != nil && r.Method == http.MethodPost {
+				captured = &capturingReader{ReadCloser: r.Body, limit: opts.BodyMaxBytes}
+				r.Body = captured
 			}
 
 			next.ServeHTTP(lrw, r)
 
-			logger.Info("http request",
+			fields := append(TraceFields(r.Context()),
+				zap.String("request_id", RequestIDFromContext(r.Context())),
 				zap.String("method", r.Method),
 				zap.String("path", r.URL.Path),
 				zap.Int("status", lrw.status),
+				zap.Int("size", lrw.size),
 				zap.Duration("duration", time.Since(start)),
-				zap.ByteString("body", body),
 				zap.String("remote_ip", r.RemoteAddr),
 				zap.String("user_agent", r.UserAgent()),
 			)
+
+			if captured != nil {
+				body := captured.buf.String()
+				if opts.BodyMode == BodyRedacted {
+					body = redactor.redact(body)
+				}
+				fields = append(fields,
+					zap.String("body", body),
+					zap.Bool("body_truncated", captured.truncated),
+				)
+			}
+
+			if isRedirect(r, lrw.status) {
+				redirectLogger.Info("http redirect", fields...)
+				return
+			}
+			logger.Info("http request", fields...)
 		})
 	}
 }
@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	compres "github.com/Oleg2210/goshortener/pkg/middleware/compress"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLoggingMiddlewareRedactsAndTruncates(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	mw := LoggingMiddleware(zap.New(core), Options{
+		BodyMode:          BodyRedacted,
+		BodyMaxBytes:      64,
+		RedactQueryParams: []string{"token"},
+		RedactJSONFields:  []string{"password"},
+	})
+
+	handler := RequestIDMiddleware(mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("ok"))
+	})))
+
+	body := `{"url":"https://a.example/?x=1&token=abc","password":"p"}` + strings.Repeat(" ", 100)
+	request := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+	request.Header.Set(RequestIDHeader, "req-1")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, "req-1", recorder.Header().Get(RequestIDHeader))
+
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "req-1", fields["request_id"])
+	assert.Equal(t, int64(2), fields["size"])
+	assert.Equal(t, true, fields["body_truncated"])
+	assert.NotContains(t, fields["body"], "abc")
+	assert.Contains(t, fields["body"], "token=[REDACTED]")
+	assert.Contains(t, fields["body"], `"password":"[REDACTED]"`)
+}
+
+func TestRedirectLogsAreSampled(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	mw := LoggingMiddleware(zap.New(core), Options{
+		BodyMode:         BodyOff,
+		SampleInitial:    2,
+		SampleThereafter: 5,
+	})
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	}))
+
+	for i := 0; i < 12; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abc", nil))
+	}
+
+	assert.Equal(t, 4, logs.Len())
+}
+
+func TestRequestIDGeneratedForInvalidHeader(t *testing.T) {
+	var got string
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = RequestIDFromContext(r.Context())
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set(RequestIDHeader, "bad id\n")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Len(t, got, 32)
+	assert.Equal(t, got, recorder.Header().Get(RequestIDHeader))
+}
+
+func TestLoggingMiddlewareLogsDecompressedBody(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	mw := LoggingMiddleware(zap.New(core), Options{
+		BodyMode:         BodyRedacted,
+		BodyMaxBytes:     1024,
+		RedactJSONFields: []string{"password"},
+	})
+
+	// тот же порядок, что и в main
+	handler := compres.Middleware(compres.DefaultOptions())(mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	})))
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte(`{"url":"https://a.example/","password":"p"}`))
+	zw.Close()
+
+	request := httptest.NewRequest(http.MethodPost, "/api/shorten", &compressed)
+	request.Header.Set("Content-Encoding", "gzip")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, `{"url":"https://a.example/","password":"[REDACTED]"}`, fields["body"])
+}
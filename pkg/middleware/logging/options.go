@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type BodyMode string
+
+const (
+	// тело запроса не логируется
+	BodyOff BodyMode = "off"
+	// логируются первые BodyMaxBytes байт тела как есть
+	BodyTruncated BodyMode = "truncated"
+	// как BodyTruncated, но с вырезанными секретами
+	BodyRedacted BodyMode = "redacted"
+)
+
+const redactedValue = "[REDACTED]"
+
+func ParseBodyMode(s string) (BodyMode, error) {
+	switch mode := BodyMode(s); mode {
+	case BodyOff, BodyTruncated, BodyRedacted:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown body log mode %q", s)
+	}
+}
+
+type Options struct {
+	BodyMode     BodyMode
+	BodyMaxBytes int
+	// имена query-параметров, значения которых вырезаются из url в теле
+	RedactQueryParams []string
+	// имена полей JSON, значения которых вырезаются
+	RedactJSONFields []string
+
+	// семплирование логов редиректов: в каждую секунду пишутся первые SampleInitial
+	// записей, затем каждая SampleThereafter-я; 0 отключает семплирование
+	SampleInitial    int
+	SampleThereafter int
+}
+
+type redactor struct {
+	query *regexp.Regexp
+	json  *regexp.Regexp
+}
+
+func alternation(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, n := range names {
+		quoted = append(quoted, regexp.QuoteMeta(n))
+	}
+	return strings.Join(quoted, "|")
+}
+
+func newRedactor(queryParams, jsonFields []string) *redactor {
+	r := &redactor{}
+
+	if len(queryParams) > 0 {
+		r.query = regexp.MustCompile(`(?i)([?&](?:` + alternation(queryParams) + `)=)[^&#\s"']*`)
+	}
+	if len(jsonFields) > 0 {
+		r.json = regexp.MustCompile(`(?i)("(?:` + alternation(jsonFields) + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+	}
+
+	return r
+}
+
+// redact работает по регулярным выражениям, а не через разбор JSON,
+// чтобы справляться и с обрезанным телом, и с text/plain.
+func (r *redactor) redact(body string) string {
+	if r.json != nil {
+		body = r.json.ReplaceAllString(body, `${1}"`+redactedValue+`"`)
+	}
+	if r.query != nil {
+		body = r.query.ReplaceAllString(body, "${1}"+redactedValue)
+	}
+	return body
+}
@@ -0,0 +1,4 @@
+// Package shortenerpb содержит сгенерированный gRPC-контракт из api/proto/shortener.proto.
+package shortenerpb
+
+//go:generate protoc --proto_path=../../api/proto --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative shortener.proto